@@ -1,10 +1,10 @@
 package primus
 
 type Access struct {
-	Sign    []*AccessToken
-	Block   []*AccessToken
-	UnBlock []*AccessToken
-	Modify  []*AccessToken
+	Sign    []*AccessToken `json:"sign"`
+	Block   []*AccessToken `json:"block"`
+	UnBlock []*AccessToken `json:"unblock"`
+	Modify  []*AccessToken `json:"modify"`
 }
 
 func NewAccess(signing, block, unBlock, modify []*AccessToken) *Access {
@@ -16,6 +16,9 @@ func NewAccess(signing, block, unBlock, modify []*AccessToken) *Access {
 	}
 }
 
+// accessBlobNames are the names of the blobs returned by Access.Blobs.
+var accessBlobNames = []BlobName{BlobNames.Signing, BlobNames.Block, BlobNames.UnBlock, BlobNames.Modify}
+
 func (a *Access) Blobs() []AccessBlob {
 	var res = make([]AccessBlob, 4)
 	res[0] = a.Sign
@@ -52,9 +55,9 @@ func (a *Access) Serialize() []byte {
 	return payload.Bytes()
 }
 
+// Deserialize decodes Serialize or ToModifyPayload output.
 func (a *Access) Deserialize(bs []byte) error {
-	var payload = Payload{}
-	err := payload.Deserialize(bs)
+	payload, err := optionallyCutLengthHeaderDecodePayload(bs)
 	if err != nil {
 		return err
 	}
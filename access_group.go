@@ -1,9 +1,9 @@
 package primus
 
 import (
-	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"github.com/samber/lo"
 )
 
@@ -18,6 +18,7 @@ func (g AccessGroup) MarshalJSON() ([]byte, error) {
 		Name       string   `json:"name"`
 		Quorum     int      `json:"quorum"`
 		PublicKeys []string `json:"public_keys"`
+		KeyNames   []string `json:"key_names,omitempty"`
 	}{
 		Name:   g.Name,
 		Quorum: g.Quorum,
@@ -25,17 +26,24 @@ func (g AccessGroup) MarshalJSON() ([]byte, error) {
 			return hex.EncodeToString(item.GetEncoded())
 		}),
 	}
+	for i, publicKey := range g.PublicKeys {
+		if named, ok := publicKey.(NamedPublicKey); ok && len(named.GetName()) > 0 {
+			if obj.KeyNames == nil {
+				obj.KeyNames = make([]string, len(g.PublicKeys))
+			}
+			obj.KeyNames[i] = named.GetName()
+		}
+	}
 	return json.Marshal(obj)
 }
 
+// Count returns how many of the PKIX keys are keys of the group, compared by their canonical
+// encoding.
 func (g *AccessGroup) Count(inputAsn1PubKey [][]byte) int {
 	var count = 0
-	var gPks = lo.Map(g.PublicKeys, func(item Publickey, index int) []byte {
-		return item.GetEncoded()
-	})
 	for _, pk := range inputAsn1PubKey {
-		if lo.ContainsBy(gPks, func(item []byte) bool {
-			return bytes.Equal(item, pk)
+		if lo.ContainsBy(g.PublicKeys, func(item Publickey) bool {
+			return samePublicKey(item.GetEncoded(), pk)
 		}) {
 			count++
 		}
@@ -43,6 +51,40 @@ func (g *AccessGroup) Count(inputAsn1PubKey [][]byte) int {
 	return count
 }
 
+// Validate parses the keys of the group and checks that they're distinct and that the quorum
+// can be reached.
+func (g *AccessGroup) Validate() error {
+	var keys []*ParsedPublicKey
+	for i, publicKey := range g.PublicKeys {
+		parsed, err := ParsePublicKey(publicKey)
+		if err != nil {
+			return fmt.Errorf("group %q, key %d: %w", g.Name, i, err)
+		}
+		if lo.ContainsBy(keys, parsed.Equal) {
+			return fmt.Errorf("group %q, key %d: duplicate key %s", g.Name, i, parsed.Fingerprint())
+		}
+		keys = append(keys, parsed)
+	}
+	if g.Quorum <= 0 || g.Quorum > len(keys) {
+		return fmt.Errorf("group %q has a quorum of %d for %d keys", g.Name, g.Quorum, len(keys))
+	}
+	return nil
+}
+
+// CheckSignatureAlgorithm reports whether every key of the group can sign with alg.
+func (g *AccessGroup) CheckSignatureAlgorithm(alg SignAlgT) error {
+	for i, publicKey := range g.PublicKeys {
+		keyAlg, err := DetectKeyAlg(publicKey.GetEncoded())
+		if err != nil {
+			return fmt.Errorf("group %q, key %d: %w", g.Name, i, err)
+		}
+		if !keyAlg.IsCompatible(alg) {
+			return fmt.Errorf("group %q, key %d: %s keys can't sign with %s", g.Name, i, keyAlg, alg)
+		}
+	}
+	return nil
+}
+
 func (g *AccessGroup) Serialize(p *Payload) {
 	if namingSupport && len(g.Name) > 0 {
 		p.addBs(LABEL_UTF8STRING, []byte(g.Name))
@@ -1,9 +1,13 @@
 package primus
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/samber/lo"
+	"time"
 )
 
 type ApprovalTokenOpType int
@@ -164,3 +168,129 @@ func (t *ApprovalToken) Deserialize(bs []byte) error {
 	t.Operation = operation
 	return nil
 }
+
+// Time returns the HSM time embedded in the token's timestamp, if any.
+func (t *ApprovalToken) Time() (time.Time, bool) {
+	if len(t.Timestamp) == 0 {
+		return time.Time{}, false
+	}
+	_, seconds := DecodePrimusTimestamp(t.Timestamp)
+	if seconds == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// VerifyTimestamp verifies the signature of the HSM integrity key over the timestamp.
+func (t *ApprovalToken) VerifyTimestamp(integrityKey crypto.PublicKey) error {
+	if len(t.Timestamp) == 0 {
+		return ErrMissingTimestamp
+	}
+	if t.TimestampSignature == nil {
+		return MissingFieldError{Field: "timestamp signature"}
+	}
+	alg := FindSignatureAlgorithm(t.TimestampSignature.signAlgorithm)
+	if alg == nil {
+		return fmt.Errorf("unsupported timestamp signature algorithm %q", t.TimestampSignature.signAlgorithm)
+	}
+	return alg.VerifyMessage(integrityKey, t.Timestamp, t.TimestampSignature.signature)
+}
+
+// Authorize signs the serialized token with signer and returns the AuthorizationToken carrying
+// the signature and the signer's public key.
+func (t *ApprovalToken) Authorize(signer crypto.Signer, alg SignAlgT) (*AuthorizationToken, error) {
+	return t.authorize(signer, alg, nil, nil)
+}
+
+// DigestedApproval is a serialized approval token bound to its digest, hashed once so that
+// every signer can authorize it and every returned token can be verified without rehashing.
+type DigestedApproval struct {
+	alg    SignAlgT
+	bytes  []byte
+	digest []byte
+}
+
+// Digest hashes the serialized token as alg does before signing.
+func (t *ApprovalToken) Digest(alg SignAlgT) (*DigestedApproval, error) {
+	return newDigestedApproval(t.Serialize(), alg)
+}
+
+// DigestBytes is Digest for an approval token as received from the HSM.
+func DigestBytes(approvalToken []byte, alg SignAlgT) (*DigestedApproval, error) {
+	if err := new(ApprovalToken).Deserialize(approvalToken); err != nil {
+		return nil, err
+	}
+	return newDigestedApproval(approvalToken, alg)
+}
+
+func newDigestedApproval(approvalToken []byte, alg SignAlgT) (*DigestedApproval, error) {
+	obj, ok := FindSignatureAlgorithm(alg).(DigestAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf("signature algorithm %q doesn't sign digests", alg)
+	}
+	digest, err := obj.Digest(bytes.NewReader(approvalToken))
+	if err != nil {
+		return nil, err
+	}
+	return &DigestedApproval{alg: alg, bytes: approvalToken, digest: digest}, nil
+}
+
+func (d *DigestedApproval) Algorithm() SignAlgT {
+	return d.alg
+}
+
+func (d *DigestedApproval) Digest() []byte {
+	return d.digest
+}
+
+// Authorize signs the digest with signer, the approval token isn't hashed again.
+func (d *DigestedApproval) Authorize(signer crypto.Signer) (*AuthorizationToken, error) {
+	return authorizeChallenge(d.bytes, signer, d.alg, nil, d.digest)
+}
+
+// AuthorizeBytes is Authorize for an approval token as received from the HSM. The bytes are
+// signed as they are instead of the re-serialized token.
+func AuthorizeBytes(approvalToken []byte, signer crypto.Signer, alg SignAlgT) (*AuthorizationToken, error) {
+	if err := new(ApprovalToken).Deserialize(approvalToken); err != nil {
+		return nil, err
+	}
+	return authorizeChallenge(approvalToken, signer, alg, nil, nil)
+}
+
+func (t *ApprovalToken) authorize(signer crypto.Signer, alg SignAlgT, chain []*x509.Certificate, digest []byte) (*AuthorizationToken, error) {
+	return authorizeChallenge(t.Serialize(), signer, alg, chain, digest)
+}
+
+func authorizeChallenge(challenge []byte, signer crypto.Signer, alg SignAlgT, chain []*x509.Certificate, digest []byte) (*AuthorizationToken, error) {
+	obj := FindSignatureAlgorithm(alg)
+	if obj == nil {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+	if err := obj.CheckKey(signer.Public()); err != nil {
+		return nil, err
+	}
+	var publicKey []byte
+	if len(chain) > 0 {
+		publicKey = encodeCertificateData(chain)
+	} else {
+		var err error
+		if publicKey, err = marshalPKIXPublicKey(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	var signature []byte
+	var err error
+	if digest != nil {
+		digestObj, ok := obj.(DigestAlgorithm)
+		if !ok {
+			return nil, fmt.Errorf("signature algorithm %q doesn't sign digests", alg)
+		}
+		signature, err = digestObj.SignDigest(signer, digest)
+	} else {
+		signature, err = obj.SignMessage(signer, challenge)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return EncodePrimusAuthorizationToken(challenge, signature, alg, publicKey, len(chain) > 0)
+}
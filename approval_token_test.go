@@ -1,6 +1,10 @@
 package primus
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/hex"
 	"fmt"
 	"github.com/samber/lo"
@@ -53,3 +57,51 @@ func TestParse(t *testing.T) {
 	//
 	//spew.Dump(pp.getParts())
 }
+
+func TestAuthorize(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("content to be sign"), "gt_ec_08")
+
+	token := lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+	impl := lo.Must(NewPrimusAuthorizationTokenImpl(token.GetEncoding()))
+	if !bytes.Equal(impl.ApprovalTokenBytes, approval.Serialize()) {
+		t.Fatal("invalid approval token")
+	}
+	if alg := ExtractSignAlgorithm(impl.DerSignatureBytes); alg != EcdsaSignAlg.SHA256withECDSA {
+		t.Fatal("invalid algorithm", alg)
+	}
+	if !FindEcdsaByName(EcdsaSignAlg.SHA256withECDSA).Verify(&priv.PublicKey, impl.ApprovalTokenBytes, impl.VerifySignatureBytes) {
+		t.Fatal("invalid signature")
+	}
+
+	p384 := lo.Must(ecdsa.GenerateKey(elliptic.P384(), rand.Reader))
+	if _, err := approval.Authorize(p384, EcdsaSignAlg.SHA256withECDSA); err == nil {
+		t.Fatal("SHA256withECDSA must be rejected for P-384")
+	}
+	if _, err := approval.Authorize(p384, EcdsaSignAlg.SHA384withECDSA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approval.Authorize(priv, "SHA256withDSA"); err == nil {
+		t.Fatal("unknown algorithm must be rejected")
+	}
+}
+
+func TestAuthorizeBytes(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("content to be sign"), "gt_ec_08")
+	// an encoder may order the parts differently from Serialize
+	p := new(Payload)
+	p.addBs(LABEL_UTF8STRING, []byte(approval.KeyName))
+	p.addInt(EKA_OPERATION, int(approval.Operation))
+	p.addBs(EKA_SIGN_PAYLOAD, approval.EkaPayload)
+	received := lengthHeader(p.Bytes())
+
+	token := lo.Must(AuthorizeBytes(received, priv, EcdsaSignAlg.SHA256withECDSA))
+	verified := lo.Must(VerifyAuthorizationToken(token.GetEncoding(), nil))
+	if !bytes.Equal(verified.ApprovalTokenBytes, received) || verified.ApprovalToken.KeyName != "gt_ec_08" {
+		t.Fatal("must sign the received bytes")
+	}
+	if _, err := AuthorizeBytes([]byte("garbage"), priv, EcdsaSignAlg.SHA256withECDSA); err == nil {
+		t.Fatal("expected invalid approval token")
+	}
+}
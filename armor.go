@@ -0,0 +1,235 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TokenFormats are the text encodings of tokens. Only Armor carries a checksum, Raw, Hex, Base64
+// and Base64URL input has no integrity check.
+var TokenFormats = struct {
+	Raw       string
+	Hex       string
+	Base64    string
+	Base64URL string
+	Armor     string
+}{
+	Raw:       "raw",
+	Hex:       "hex",
+	Base64:    "base64",
+	Base64URL: "base64url",
+	Armor:     "armor",
+}
+
+var ArmorTypes = struct {
+	AuthorizationToken string
+	ApprovalToken      string
+	Access             string
+}{
+	AuthorizationToken: "PRIMUS AUTHORIZATION TOKEN",
+	ApprovalToken:      "PRIMUS APPROVAL TOKEN",
+	Access:             "PRIMUS ACCESS POLICY",
+}
+
+const armorChecksumHeader = "Checksum"
+
+var ErrArmorChecksum = errors.New("armor checksum mismatch")
+
+// TokenText is a binary token together with its text encoding.
+type TokenText struct {
+	Format  string
+	Type    string            // armor type, only set for TokenFormats.Armor
+	Headers map[string]string // descriptive armor headers, not covered by the checksum
+	Bytes   []byte
+}
+
+// Encode renders b.Bytes in b.Format. An empty format is raw.
+func (b *TokenText) Encode() ([]byte, error) {
+	switch b.Format {
+	case "", TokenFormats.Raw:
+		return b.Bytes, nil
+	case TokenFormats.Hex:
+		return []byte(hex.EncodeToString(b.Bytes)), nil
+	case TokenFormats.Base64:
+		return []byte(base64.StdEncoding.EncodeToString(b.Bytes)), nil
+	case TokenFormats.Base64URL:
+		return []byte(base64.RawURLEncoding.EncodeToString(b.Bytes)), nil
+	case TokenFormats.Armor:
+		headers := make(map[string]string, len(b.Headers)+1)
+		for k, v := range b.Headers {
+			headers[k] = v
+		}
+		headers[armorChecksumHeader] = armorChecksum(b.Bytes)
+		return pem.EncodeToMemory(&pem.Block{Type: b.Type, Headers: headers, Bytes: b.Bytes}), nil
+	default:
+		return nil, fmt.Errorf("unknown token format %q", b.Format)
+	}
+}
+
+// DecodeTokenText detects the encoding of data, decodes it and checks that the result is a
+// Primus payload. Text containing an armor line is decoded as armor and must carry a valid
+// checksum. Other input has no integrity check, a corrupted copy which still decodes is accepted.
+func DecodeTokenText(data []byte) (*TokenText, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.Contains(trimmed, []byte("-----BEGIN ")) {
+		return decodeArmor(trimmed)
+	}
+	if compact := removeSpaces(trimmed); isText(compact) {
+		for _, candidate := range []struct {
+			format string
+			decode func(string) ([]byte, error)
+		}{
+			{TokenFormats.Hex, hex.DecodeString},
+			{TokenFormats.Base64, base64.StdEncoding.DecodeString},
+			{TokenFormats.Base64URL, base64.RawURLEncoding.DecodeString},
+			{TokenFormats.Base64URL, base64.URLEncoding.DecodeString},
+		} {
+			bs, err := candidate.decode(compact)
+			if err == nil && isPrimusPayload(bs) {
+				return &TokenText{Format: candidate.format, Bytes: bs}, nil
+			}
+		}
+	}
+	if isPrimusPayload(data) {
+		return &TokenText{Format: TokenFormats.Raw, Bytes: data}, nil
+	}
+	return nil, errors.New("unrecognized token encoding")
+}
+
+func decodeArmor(data []byte) (*TokenText, error) {
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid armor")
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, errors.New("trailing data after armor")
+	}
+	checksum, ok := block.Headers[armorChecksumHeader]
+	if !ok {
+		return nil, errors.New("armor has no checksum")
+	}
+	if checksum != armorChecksum(block.Bytes) {
+		return nil, ErrArmorChecksum
+	}
+	if !isPrimusPayload(block.Bytes) {
+		return nil, errors.New("armor does not contain a Primus payload")
+	}
+	delete(block.Headers, armorChecksumHeader)
+	return &TokenText{Format: TokenFormats.Armor, Type: block.Type, Headers: block.Headers, Bytes: block.Bytes}, nil
+}
+
+func armorChecksum(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:4])
+}
+
+func isPrimusPayload(bs []byte) bool {
+	if len(bs) == 0 {
+		return false
+	}
+	_, err := optionallyCutLengthHeaderDecodePayload(bs)
+	return err == nil
+}
+
+func isText(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func removeSpaces(bs []byte) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(bs))
+}
+
+func approvalTokenHeaders(t *ApprovalToken) map[string]string {
+	headers := map[string]string{
+		"Operation": t.Operation.String(),
+		"Key-Name":  t.KeyName,
+	}
+	if issuedAt, ok := t.Time(); ok {
+		headers["Timestamp"] = issuedAt.UTC().Format(time.RFC3339)
+	}
+	return headers
+}
+
+// Export encodes the token in format, armored tokens get the operation, key name and timestamp
+// of the approval token as headers.
+func (t *AuthorizationToken) Export(format string) ([]byte, error) {
+	text := &TokenText{Format: format, Type: ArmorTypes.AuthorizationToken, Bytes: t.data}
+	if format == TokenFormats.Armor {
+		approvalToken, err := t.GetApprovalToken()
+		if err != nil {
+			return nil, err
+		}
+		text.Headers = approvalTokenHeaders(approvalToken)
+		if der, err := t.GetDerSignatureBytes(); err == nil && len(der) > 0 {
+			text.Headers["Signature-Algorithm"] = ExtractSignAlgorithm(der).String()
+		}
+	}
+	return text.Encode()
+}
+
+// Encode encodes the token in the format it was imported or created with.
+func (t *AuthorizationToken) Encode() ([]byte, error) {
+	return t.Export(t.format)
+}
+
+// ImportAuthorizationToken decodes a token in any of TokenFormats, the detected format is kept.
+func ImportAuthorizationToken(data []byte) (*AuthorizationToken, error) {
+	text, err := DecodeTokenText(data)
+	if err != nil {
+		return nil, err
+	}
+	if text.Type != "" && text.Type != ArmorTypes.AuthorizationToken {
+		return nil, fmt.Errorf("unexpected armor type %q", text.Type)
+	}
+	token := NewPrimusAuthorizationToken(text.Bytes, text.Format)
+	if _, err := token.GetApprovalToken(); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (t *ApprovalToken) Export(format string) ([]byte, error) {
+	text := &TokenText{Format: format, Type: ArmorTypes.ApprovalToken, Bytes: t.Serialize()}
+	if format == TokenFormats.Armor {
+		text.Headers = approvalTokenHeaders(t)
+	}
+	return text.Encode()
+}
+
+// ImportApprovalToken decodes an approval token in any of TokenFormats. It also returns the
+// decoded bytes, which are the bytes approvers sign.
+func ImportApprovalToken(data []byte) (*ApprovalToken, []byte, error) {
+	text, err := DecodeTokenText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if text.Type != "" && text.Type != ArmorTypes.ApprovalToken {
+		return nil, nil, fmt.Errorf("unexpected armor type %q", text.Type)
+	}
+	token := new(ApprovalToken)
+	if err := token.Deserialize(text.Bytes); err != nil {
+		return nil, nil, err
+	}
+	return token, text.Bytes, nil
+}
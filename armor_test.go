@@ -0,0 +1,86 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/samber/lo"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizationTokenExportImport(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("content to be sign"), "gt_ec_08")
+	token := lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+
+	for _, format := range []string{TokenFormats.Raw, TokenFormats.Hex, TokenFormats.Base64, TokenFormats.Base64URL, TokenFormats.Armor} {
+		text := lo.Must(token.Export(format))
+		imported, err := ImportAuthorizationToken(text)
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if imported.GetFormat() != format || !bytes.Equal(imported.GetEncoding(), token.GetEncoding()) {
+			t.Fatal("invalid import", format, imported.GetFormat())
+		}
+		if !bytes.Equal(lo.Must(imported.Encode()), text) {
+			t.Fatal("Encode must keep the imported format", format)
+		}
+	}
+
+	armored := string(lo.Must(token.Export(TokenFormats.Armor)))
+	if !strings.HasPrefix(armored, "-----BEGIN PRIMUS AUTHORIZATION TOKEN-----\n") ||
+		!strings.Contains(armored, "Key-Name: gt_ec_08\n") || !strings.Contains(armored, "Operation: SIGN\n") ||
+		!strings.Contains(armored, "Signature-Algorithm: SHA256withECDSA\n") {
+		t.Fatal("invalid armor", armored)
+	}
+
+	// a hex copy wrapped by a mail client
+	wrapped := lo.Must(token.Export(TokenFormats.Hex))
+	wrapped = append(append(append([]byte{}, wrapped[:40]...), "\r\n  "...), wrapped[40:]...)
+	if _, err := ImportAuthorizationToken(wrapped); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(armored, "\n")
+	for i, line := range lines {
+		if i > 0 && len(line) == 64 {
+			lines[i] = line[:10] + lo.Ternary(line[10] == 'A', "B", "A") + line[11:]
+			break
+		}
+	}
+	if _, err := ImportAuthorizationToken([]byte(strings.Join(lines, "\n"))); !errors.Is(err, ErrArmorChecksum) {
+		t.Fatal("expected checksum error, got", err)
+	}
+	// text before the armor doesn't make it fall back to the unchecked encodings
+	quoted := "Forwarded message:\n" + strings.Join(lines, "\n")
+	if _, err := ImportAuthorizationToken([]byte(quoted)); !errors.Is(err, ErrArmorChecksum) {
+		t.Fatal("expected checksum error, got", err)
+	}
+
+	if _, _, err := ImportApprovalToken([]byte(armored)); err == nil {
+		t.Fatal("armor type must be checked")
+	}
+	if _, err := ImportAuthorizationToken([]byte("not a token")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestApprovalTokenExportImport(t *testing.T) {
+	approval := new(ApprovalToken)
+	lo.Must0(approval.Deserialize(mustDecode(testApprovalTokenWithTime)))
+
+	armored := lo.Must(approval.Export(TokenFormats.Armor))
+	if !strings.Contains(string(armored), "Timestamp: 2024-10-21T04:40:24Z\n") {
+		t.Fatal("invalid armor", string(armored))
+	}
+	decoded, bs, err := ImportApprovalToken(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bs, mustDecode(testApprovalTokenWithTime)) || decoded.KeyName != "gt_ec_08" {
+		t.Fatal("invalid approval token")
+	}
+}
@@ -0,0 +1,168 @@
+package primus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/samber/lo"
+)
+
+var ErrApprovalTokenMismatch = errors.New("authorization token signs a different approval token")
+var ErrDuplicateSigner = errors.New("signer already approved")
+
+// AuthorizationBundle collects the AuthorizationTokens of several approvers over the same approval token.
+type AuthorizationBundle struct {
+	// Options are applied to every token added to the bundle. A replay cache should not be set here,
+	// the tokens are used again when the bundle is submitted.
+	Options *VerifyOptions
+
+	approvalTokenBytes []byte
+	tokens             []*AuthorizationToken
+	verified           []*VerifiedAuthorization
+}
+
+// NewAuthorizationBundle creates a bundle for approvalTokenBytes, if nil the first added token decides.
+func NewAuthorizationBundle(approvalTokenBytes []byte) *AuthorizationBundle {
+	return &AuthorizationBundle{approvalTokenBytes: approvalTokenBytes}
+}
+
+func (b *AuthorizationBundle) ApprovalTokenBytes() []byte {
+	return b.approvalTokenBytes
+}
+
+func (b *AuthorizationBundle) Tokens() []*AuthorizationToken {
+	return b.tokens
+}
+
+func (b *AuthorizationBundle) Verified() []*VerifiedAuthorization {
+	return b.verified
+}
+
+func (b *AuthorizationBundle) Len() int {
+	return len(b.tokens)
+}
+
+// Signers returns the encoded public keys of all approvers.
+func (b *AuthorizationBundle) Signers() [][]byte {
+	return lo.Map(b.verified, func(item *VerifiedAuthorization, index int) []byte {
+		return item.PublicKeyEncoded
+	})
+}
+
+// Add verifies token and adds it to the bundle.
+func (b *AuthorizationBundle) Add(token *AuthorizationToken) error {
+	verified, err := VerifyAuthorizationToken(token.GetEncoding(), b.Options)
+	if err != nil {
+		return err
+	}
+	if b.approvalTokenBytes != nil && !bytes.Equal(b.approvalTokenBytes, verified.ApprovalTokenBytes) {
+		return ErrApprovalTokenMismatch
+	}
+	for _, item := range b.verified {
+		if item.Key.Equal(verified.Key) {
+			return ErrDuplicateSigner
+		}
+	}
+	if b.approvalTokenBytes == nil {
+		b.approvalTokenBytes = verified.ApprovalTokenBytes
+	}
+	b.tokens = append(b.tokens, token)
+	b.verified = append(b.verified, verified)
+	return nil
+}
+
+type AccessGroupProgress struct {
+	Name      string      `json:"name"`
+	Quorum    int         `json:"quorum"`
+	Approvals int         `json:"approvals"`
+	Missing   []Publickey `json:"-"` // group keys which have not approved yet
+}
+
+func (p AccessGroupProgress) Satisfied() bool {
+	return p.Approvals >= p.Quorum
+}
+
+type AccessTokenProgress struct {
+	Name      string                `json:"name"`
+	Satisfied bool                  `json:"satisfied"`
+	Groups    []AccessGroupProgress `json:"groups"`
+}
+
+// QuorumProgress reports which tokens of an AccessBlob are satisfied by a bundle. The blob is
+// satisfied as soon as one of its tokens is, a token needs the quorum of each of its groups.
+type QuorumProgress struct {
+	Satisfied bool                  `json:"satisfied"`
+	Tokens    []AccessTokenProgress `json:"tokens"`
+}
+
+func (b *AuthorizationBundle) Progress(blob AccessBlob) *QuorumProgress {
+	signers := b.Signers()
+	var progress = new(QuorumProgress)
+	for _, token := range blob {
+		tokenProgress := AccessTokenProgress{Name: token.Name, Satisfied: true}
+		for _, group := range token.Groups {
+			groupProgress := AccessGroupProgress{
+				Name:      group.Name,
+				Quorum:    group.Quorum,
+				Approvals: group.Count(signers),
+			}
+			for _, publicKey := range group.PublicKeys {
+				if !lo.ContainsBy(signers, func(item []byte) bool {
+					return samePublicKey(item, publicKey.GetEncoded())
+				}) {
+					groupProgress.Missing = append(groupProgress.Missing, publicKey)
+				}
+			}
+			tokenProgress.Satisfied = tokenProgress.Satisfied && groupProgress.Satisfied()
+			tokenProgress.Groups = append(tokenProgress.Groups, groupProgress)
+		}
+		progress.Satisfied = progress.Satisfied || tokenProgress.Satisfied
+		progress.Tokens = append(progress.Tokens, tokenProgress)
+	}
+	return progress
+}
+
+type authorizationBundleJson struct {
+	ApprovalToken       string   `json:"approval_token"`
+	AuthorizationTokens []string `json:"authorization_tokens"`
+}
+
+// Serialize encodes the bundle as JSON with hex encoded tokens, to be passed between approvers.
+func (b *AuthorizationBundle) Serialize() []byte {
+	bs, err := json.MarshalIndent(authorizationBundleJson{
+		ApprovalToken: hex.EncodeToString(b.approvalTokenBytes),
+		AuthorizationTokens: lo.Map(b.tokens, func(item *AuthorizationToken, index int) string {
+			return hex.EncodeToString(item.GetEncoding())
+		}),
+	}, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+// Deserialize decodes the output of Serialize and verifies every token again.
+func (b *AuthorizationBundle) Deserialize(bs []byte) error {
+	var obj authorizationBundleJson
+	if err := json.Unmarshal(bs, &obj); err != nil {
+		return err
+	}
+	approvalTokenBytes, err := hex.DecodeString(obj.ApprovalToken)
+	if err != nil {
+		return err
+	}
+	b.approvalTokenBytes = copySlice(approvalTokenBytes)
+	b.tokens = nil
+	b.verified = nil
+	for _, item := range obj.AuthorizationTokens {
+		data, err := hex.DecodeString(item)
+		if err != nil {
+			return err
+		}
+		if err = b.Add(NewPrimusAuthorizationToken(data, "")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
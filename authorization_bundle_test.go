@@ -0,0 +1,53 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestAuthorizationBundle(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	var publicKeys []Publickey
+	for _, name := range []string{"alice", "bob", "carol"} {
+		priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+		keys = append(keys, priv)
+		publicKeys = append(publicKeys, NewPublicKeyImpl(name, lo.Must(x509.MarshalPKIXPublicKey(&priv.PublicKey))))
+	}
+	blob := AccessBlob{{
+		Name:   "default",
+		Groups: []*AccessGroup{{Name: "approvers", Quorum: 2, PublicKeys: publicKeys}},
+	}}
+
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "key")
+	bundle := NewAuthorizationBundle(approval.Serialize())
+	lo.Must0(bundle.Add(lo.Must(approval.Authorize(keys[0], EcdsaSignAlg.SHA256withECDSA))))
+
+	progress := bundle.Progress(blob)
+	if progress.Satisfied || progress.Tokens[0].Groups[0].Approvals != 1 || len(progress.Tokens[0].Groups[0].Missing) != 2 {
+		t.Fatal("invalid progress", progress)
+	}
+
+	if err := bundle.Add(lo.Must(approval.Authorize(keys[0], EcdsaSignAlg.SHA256withECDSA))); !errors.Is(err, ErrDuplicateSigner) {
+		t.Fatal("expected duplicate signer, got", err)
+	}
+	other := NewPrimusApprovalToken(ApprovalTokenOp.BLOCK, nil, "key")
+	if err := bundle.Add(lo.Must(other.Authorize(keys[1], EcdsaSignAlg.SHA256withECDSA))); !errors.Is(err, ErrApprovalTokenMismatch) {
+		t.Fatal("expected mismatch, got", err)
+	}
+
+	lo.Must0(bundle.Add(lo.Must(approval.Authorize(keys[1], EcdsaSignAlg.SHA256withECDSA))))
+	if progress = bundle.Progress(blob); !progress.Satisfied || progress.Tokens[0].Groups[0].Missing[0] != publicKeys[2] {
+		t.Fatal("invalid progress", progress)
+	}
+
+	decoded := new(AuthorizationBundle)
+	lo.Must0(decoded.Deserialize(bundle.Serialize()))
+	if decoded.Len() != 2 || !decoded.Progress(blob).Satisfied {
+		t.Fatal("invalid decoded bundle")
+	}
+}
@@ -1,7 +1,8 @@
 package primus
 
 import (
-	"errors"
+	"crypto/x509"
+	"fmt"
 	"github.com/samber/lo"
 )
 
@@ -16,6 +17,8 @@ type AuthorizationTokenImpl struct {
 	PublicKeyEncodedBytes []byte
 	ApprovalTokenBytes    []byte
 	ApprovalToken         *ApprovalToken
+	// Certificates is the signer certificate followed by its chain, for tokens carrying CERTIFICATEDATA_BYTES.
+	Certificates []*x509.Certificate
 }
 
 func NewPrimusAuthorizationToken(data []byte, format string) *AuthorizationToken {
@@ -29,22 +32,34 @@ func NewPrimusAuthorizationTokenImpl(data []byte) (*AuthorizationTokenImpl, erro
 	}
 	derSignatureBytes := payload.findData(DER_SIGNATURE)
 	approvalTokenBytes := payload.findData(APPROVAL_TOKEN)
-	tt := new(ApprovalToken)
-	if err := tt.Deserialize(approvalTokenBytes); err != nil {
-		return nil, err
-	}
 	ret := &AuthorizationTokenImpl{
 		DerSignatureBytes:     derSignatureBytes,
 		VerifySignatureBytes:  underifyOidAndSig(derSignatureBytes),
 		PublicKeyEncodedBytes: payload.findData(PUBLIC_KEY_ENCODED),
 		ApprovalTokenBytes:    approvalTokenBytes,
-		ApprovalToken:         tt,
 	}
-	if len(ret.DerSignatureBytes) == 0 || len(ret.PublicKeyEncodedBytes) == 0 ||
-		len(ret.VerifySignatureBytes) == 0 || len(ret.ApprovalTokenBytes) == 0 ||
-		ret.ApprovalToken == nil {
-		return nil, errors.New("invalid authorization token")
+	if len(ret.ApprovalTokenBytes) == 0 {
+		return nil, MissingFieldError{Field: "APPROVAL_TOKEN"}
+	}
+	if len(ret.DerSignatureBytes) == 0 || len(ret.VerifySignatureBytes) == 0 {
+		return nil, MissingFieldError{Field: "DER_SIGNATURE"}
+	}
+	if certData := payload.findData(CERTIFICATEDATA_BYTES); len(certData) > 0 {
+		if ret.Certificates, err = parseCertificateData(certData); err != nil {
+			return nil, err
+		}
+		if len(ret.PublicKeyEncodedBytes) == 0 {
+			ret.PublicKeyEncodedBytes = ret.Certificates[0].RawSubjectPublicKeyInfo
+		}
+	}
+	if len(ret.PublicKeyEncodedBytes) == 0 {
+		return nil, MissingFieldError{Field: "PUBLIC_KEY_ENCODED"}
 	}
+	tt := new(ApprovalToken)
+	if err := tt.Deserialize(approvalTokenBytes); err != nil {
+		return nil, err
+	}
+	ret.ApprovalToken = tt
 	return ret, nil
 }
 
@@ -54,14 +69,30 @@ func NewPrimusAuthorizationTokenImpl(data []byte) (*AuthorizationTokenImpl, erro
 func NewPrimusAuthorizationTokenEncode(
 	challenge []byte,
 	signature []byte,
-	signatureAlg EcdsaSignAlgT,
+	signatureAlg SignAlgT,
 	publicKey []byte,
 	certificateSupport bool,
 ) *AuthorizationToken {
+	token, err := EncodePrimusAuthorizationToken(challenge, signature, signatureAlg, publicKey, certificateSupport)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// EncodePrimusAuthorizationToken is NewPrimusAuthorizationTokenEncode returning an error for an
+// unknown signature algorithm.
+func EncodePrimusAuthorizationToken(
+	challenge []byte,
+	signature []byte,
+	signatureAlg SignAlgT,
+	publicKey []byte,
+	certificateSupport bool,
+) (*AuthorizationToken, error) {
 	if len(signature) > 0 {
 		signature = DerifyOidAndSig(signatureAlg, signature)
 		if len(signature) == 0 {
-			panic("invalid signature algorithm")
+			return nil, fmt.Errorf("unknown signature algorithm %s", signatureAlg)
 		}
 	}
 	var payload = new(Payload)
@@ -74,7 +105,7 @@ func NewPrimusAuthorizationTokenEncode(
 	if len(publicKey) > 0 {
 		payload.addBs(lo.Ternary(certificateSupport, CERTIFICATEDATA_BYTES, PUBLIC_KEY_ENCODED), publicKey)
 	}
-	return NewPrimusAuthorizationToken(lengthHeader(payload.Bytes()), "")
+	return NewPrimusAuthorizationToken(lengthHeader(payload.Bytes()), ""), nil
 }
 
 func (t *AuthorizationToken) FindData(typ PayloadType) ([]byte, error) {
@@ -105,8 +136,27 @@ func (t *AuthorizationToken) GetVerifySignatureBytes() ([]byte, error) {
 	return underifyOidAndSig(bs), nil
 }
 
+// GetPublicKeyEncodedBytes returns the signer key, taken from the signer certificate if the
+// token carries certificates instead of a bare key.
 func (t *AuthorizationToken) GetPublicKeyEncodedBytes() ([]byte, error) {
-	return t.FindData(PUBLIC_KEY_ENCODED)
+	bs, err := t.FindData(PUBLIC_KEY_ENCODED)
+	if err != nil || len(bs) > 0 {
+		return bs, err
+	}
+	certificates, err := t.GetCertificates()
+	if err != nil || len(certificates) == 0 {
+		return nil, err
+	}
+	return certificates[0].RawSubjectPublicKeyInfo, nil
+}
+
+// GetCertificates returns the DER certificates of CERTIFICATEDATA_BYTES, signer certificate first.
+func (t *AuthorizationToken) GetCertificates() ([]*x509.Certificate, error) {
+	bs, err := t.FindData(CERTIFICATEDATA_BYTES)
+	if err != nil || len(bs) == 0 {
+		return nil, err
+	}
+	return parseCertificateData(bs)
 }
 
 func (t *AuthorizationToken) GetApprovalTokenBytes() ([]byte, error) {
@@ -0,0 +1,94 @@
+package primus
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"slices"
+)
+
+// CertificateError is returned when the signer certificate chain is not trusted.
+type CertificateError struct {
+	Err error
+}
+
+func (e CertificateError) Error() string { return "authorization token: certificate: " + e.Err.Error() }
+
+func (e CertificateError) Unwrap() error { return e.Err }
+
+// AuthorizeWithCertificates is like Authorize but embeds chain, signer certificate first, instead of
+// the bare public key.
+func (t *ApprovalToken) AuthorizeWithCertificates(signer crypto.Signer, alg SignAlgT, chain []*x509.Certificate) (*AuthorizationToken, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("empty certificate chain")
+	}
+	leafKey, ok := chain[0].PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !leafKey.Equal(signer.Public()) {
+		return nil, errors.New("signer does not match the certificate")
+	}
+	return t.authorize(signer, alg, chain, nil)
+}
+
+func parseCertificateData(data []byte) ([]*x509.Certificate, error) {
+	certificates, err := x509.ParseCertificates(data)
+	if err != nil {
+		return nil, CertificateError{Err: err}
+	}
+	if len(certificates) == 0 {
+		return nil, MissingFieldError{Field: "CERTIFICATEDATA_BYTES"}
+	}
+	return certificates, nil
+}
+
+func encodeCertificateData(chain []*x509.Certificate) []byte {
+	var data [][]byte
+	for _, certificate := range chain {
+		data = append(data, certificate.Raw)
+	}
+	return slices.Concat(data...)
+}
+
+// verifyCertificates checks that the signer certificate matches the signer key and, if opts has
+// Roots, that it chains to them at the approval timestamp, verified by the caller against
+// opts.IntegrityKey, or else at opts.Now.
+func (t *AuthorizationTokenImpl) verifyCertificates(opts *VerifyOptions) (identity string, err error) {
+	if len(t.Certificates) == 0 {
+		if opts != nil && opts.Roots != nil {
+			return "", MissingFieldError{Field: "CERTIFICATEDATA_BYTES"}
+		}
+		return "", nil
+	}
+	leaf := t.Certificates[0]
+	if !bytes.Equal(leaf.RawSubjectPublicKeyInfo, t.PublicKeyEncodedBytes) {
+		return "", CertificateError{Err: errors.New("public key does not match the signer certificate")}
+	}
+	if opts == nil || opts.Roots == nil {
+		return "", nil
+	}
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return "", CertificateError{Err: errors.New("signer certificate is not valid for digital signatures")}
+	}
+	intermediates := x509.NewCertPool()
+	for _, certificate := range t.Certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	verifyAt := opts.now()
+	if issuedAt, ok := t.ApprovalToken.Time(); ok && opts.IntegrityKey != nil {
+		verifyAt = issuedAt
+	}
+	keyUsages := opts.CertificateKeyUsages
+	if len(keyUsages) == 0 {
+		keyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   verifyAt,
+		KeyUsages:     keyUsages,
+	})
+	if err != nil {
+		return "", CertificateError{Err: err}
+	}
+	return leaf.Subject.String(), nil
+}
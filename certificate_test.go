@@ -0,0 +1,110 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/samber/lo"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, name string, pub *ecdsa.PublicKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, usage x509.KeyUsage, notAfter time.Time) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notAfter.Add(-2 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              usage,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lo.Must(x509.ParseCertificate(der))
+}
+
+func TestAuthorizeWithCertificates(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	caKey := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	ca := newTestCertificate(t, "root", &caKey.PublicKey, nil, caKey, x509.KeyUsageCertSign, now.Add(time.Hour))
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	leaf := newTestCertificate(t, "alice", &priv.PublicKey, ca, caKey, x509.KeyUsageDigitalSignature, now.Add(time.Hour))
+
+	approval := NewPrimusApprovalTokenWithTime(ApprovalTokenOp.SIGN, []byte("payload"), "key",
+		EncodePrimusTimestamp([]byte("payload"), "integrity", now.Unix()), nil)
+	token := lo.Must(approval.AuthorizeWithCertificates(priv, EcdsaSignAlg.SHA256withECDSA, []*x509.Certificate{leaf, ca}))
+
+	if bs := lo.Must(token.GetPublicKeyEncodedBytes()); !bytes.Equal(bs, leaf.RawSubjectPublicKeyInfo) {
+		t.Fatal("invalid public key")
+	}
+	if certificates := lo.Must(token.GetCertificates()); len(certificates) != 2 || !certificates[1].Equal(ca) {
+		t.Fatal("invalid certificates")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	verified, err := VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Identity != "CN=alice" {
+		t.Fatal("invalid identity", verified.Identity)
+	}
+
+	// without roots the certificates are only matched against the signer key
+	verified = lo.Must(VerifyAuthorizationToken(token.GetEncoding(), nil))
+	if verified.Identity != "" || len(verified.Certificates) != 2 {
+		t.Fatal("identity must only be set for validated chains")
+	}
+
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(newTestCertificate(t, "other", &caKey.PublicKey, nil, caKey, x509.KeyUsageCertSign, now.Add(time.Hour)))
+	var certErr CertificateError
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: otherRoots}); !errors.As(err, &certErr) {
+		t.Fatal("expected CertificateError, got", err)
+	}
+
+	expired := newTestCertificate(t, "alice", &priv.PublicKey, ca, caKey, x509.KeyUsageDigitalSignature, now.Add(-time.Minute))
+	token = lo.Must(approval.AuthorizeWithCertificates(priv, EcdsaSignAlg.SHA256withECDSA, []*x509.Certificate{expired}))
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots}); !errors.As(err, &certErr) {
+		t.Fatal("expected CertificateError for expired certificate, got", err)
+	}
+
+	// an approval issued while the certificate was valid is accepted once its timestamp verifies
+	integrityKey := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	timestamp := EncodePrimusTimestamp([]byte("payload"), "integrity", now.Add(-time.Hour).Unix())
+	backdated := NewPrimusApprovalTokenWithTime(ApprovalTokenOp.SIGN, []byte("payload"), "key", timestamp,
+		NewPrimusSignature(EcdsaSignAlg.SHA256withECDSA, lo.Must(FindEcdsaByName(EcdsaSignAlg.SHA256withECDSA).Sign(integrityKey, timestamp))))
+	token = lo.Must(backdated.AuthorizeWithCertificates(priv, EcdsaSignAlg.SHA256withECDSA, []*x509.Certificate{expired}))
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots, IntegrityKey: &integrityKey.PublicKey}); err != nil {
+		t.Fatal(err)
+	}
+	// an unverified timestamp doesn't revive an expired certificate
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots}); !errors.As(err, &certErr) {
+		t.Fatal("expected CertificateError for backdated token, got", err)
+	}
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots, IntegrityKey: &caKey.PublicKey}); err == nil {
+		t.Fatal("expected timestamp signature error")
+	}
+
+	encipher := newTestCertificate(t, "alice", &priv.PublicKey, ca, caKey, x509.KeyUsageKeyEncipherment, now.Add(time.Hour))
+	token = lo.Must(approval.AuthorizeWithCertificates(priv, EcdsaSignAlg.SHA256withECDSA, []*x509.Certificate{encipher}))
+	if _, err = VerifyAuthorizationToken(token.GetEncoding(), &VerifyOptions{Roots: roots}); !errors.As(err, &certErr) {
+		t.Fatal("expected CertificateError for key usage, got", err)
+	}
+
+	if _, err = approval.AuthorizeWithCertificates(caKey, EcdsaSignAlg.SHA256withECDSA, []*x509.Certificate{leaf}); err == nil {
+		t.Fatal("signer must match the certificate")
+	}
+}
@@ -29,20 +29,19 @@ func TestCheck(t *testing.T) {
 		panic("parse algorithm failed")
 	}
 
-	pubKey, err := xx509.ParsePKIXPublicKey(token.PublicKeyEncodedBytes)
+	verified, err := VerifyAuthorizationToken(authorizationTokenBs, nil)
 	if err != nil {
-		return
+		t.Fatal(err)
 	}
-	pub, ok := pubKey.(*ecdsa.PublicKey)
-	if !ok {
+	if verified.Algorithm != alg {
+		t.Fatal("invalid algorithm", verified.Algorithm)
+	}
+	if _, ok := verified.PublicKey.(*ecdsa.PublicKey); !ok {
 		panic("not ecdsa public key")
 	}
 
 	fmt.Println("Algorithm")
-	spew.Dump(pub)
-
-	ok = FindEcdsaByName(alg).Verify(pub, token.ApprovalTokenBytes, token.VerifySignatureBytes)
-	fmt.Println("Result:", ok)
+	spew.Dump(verified.PublicKey)
 }
 
 func Test002(t *testing.T) {
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/donutnomad/primus"
+	"golang.org/x/term"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type approveOptions struct {
+	keystore     *primus.Keystore
+	integrityKey *primus.ParsedPublicKey
+	maxAge       time.Duration
+	now          func() time.Time
+}
+
+func runApprove(e *env, args []string) int {
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: primus approve -keystore file [flags] approval-token")
+		fmt.Fprintln(e.stderr, "       primus approve -verify [flags] authorization-token [approval-token]")
+		fmt.Fprintln(e.stderr, "\nShows the approval token and, once confirmed, signs it with the keystore key and writes")
+		fmt.Fprintln(e.stderr, "an armored authorization token. -verify checks such an authorization token.")
+		fs.PrintDefaults()
+	}
+	keystorePath := fs.String("keystore", "", "keystore of the approver key")
+	integrityKeyPath := fs.String("integrity-key", "", "public key of the HSM integrity key, to verify the timestamp")
+	maxAge := fs.Duration("max-age", 0, "refuse approval tokens whose timestamp is older, 0 disables the check")
+	algName := fs.String("alg", "", "signature algorithm, defaults to the first one of the key algorithm")
+	passphraseFile := fs.String("passphrase-file", "", "read the keystore passphrase from a file instead of the terminal")
+	yes := fs.Bool("yes", false, "approve without asking for confirmation")
+	output := fs.String("o", "", "output file, defaults to stdout")
+	verify := fs.Bool("verify", false, "verify an authorization token instead of signing")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 || (!*verify && (fs.NArg() != 1 || *keystorePath == "")) {
+		fs.Usage()
+		return exitUsage
+	}
+	opts := &approveOptions{maxAge: *maxAge, now: time.Now}
+	var err error
+	if *keystorePath != "" {
+		if opts.keystore, err = primus.LoadKeystore(*keystorePath); err != nil {
+			e.errorf("%v", err)
+			return exitUsage
+		}
+	}
+	if *integrityKeyPath != "" {
+		if opts.integrityKey, err = loadPublicKey(*integrityKeyPath); err != nil {
+			e.errorf("integrity key: %v", err)
+			return exitUsage
+		}
+	}
+	if *verify {
+		return verifyApproval(e, opts, fs.Arg(0), fs.Arg(1))
+	}
+	if fs.Arg(0) == "-" && (!*yes || *passphraseFile == "") {
+		e.errorf("stdin is needed for confirmation and passphrase, pass the approval token as file")
+		return exitUsage
+	}
+
+	data, err := readInput(e, fs.Arg(0))
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	token, approvalBytes, err := primus.ImportApprovalToken(data)
+	if err == nil {
+		err = checkApprovalParts(approvalBytes)
+	}
+	if err != nil {
+		e.errorf("approval token: %v", err)
+		return exitUsage
+	}
+	alg := primus.SignAlgT(*algName)
+	if alg == "" {
+		algs := opts.keystore.KeyAlg.SignatureAlgorithms()
+		if len(algs) == 0 {
+			e.errorf("%s keys can't sign", opts.keystore.KeyAlg)
+			return exitUsage
+		}
+		alg = algs[0].Name()
+	} else if !opts.keystore.KeyAlg.IsCompatible(alg) {
+		e.errorf("%s keys can't sign with %s", opts.keystore.KeyAlg, alg)
+		return exitUsage
+	}
+
+	summary := inspectApproval(token, &inspectOptions{integrityKey: opts.integrityKey})
+	writeApprovalSummary(e, token, summary, opts)
+	if err := checkApproval(token, summary, opts); err != nil {
+		e.errorf("refusing to approve: %v", err)
+		return exitFailure
+	}
+	publicKey, err := opts.keystore.ParsedPublicKey()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	fmt.Fprintf(e.stderr, "\nsigner: %s (%s %s) with %s\n", opts.keystore.Label, publicKey.KeyAlg(), publicKey.Fingerprint(), alg)
+
+	in := bufio.NewReader(e.stdin)
+	if !*yes {
+		fmt.Fprint(e.stderr, `Type "yes" to approve: `)
+		answer, _ := in.ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			e.errorf("not approved")
+			return exitFailure
+		}
+	}
+	passphrase, err := readPassphrase(e, in, *passphraseFile)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	signer, err := opts.keystore.Unlock(passphrase)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	authorization, err := primus.AuthorizeBytes(approvalBytes, signer, alg)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	if _, err := checkAuthorization(authorization.GetEncoding(), approvalBytes, opts); err != nil {
+		e.errorf("self check failed: %v", err)
+		return exitFailure
+	}
+	armored, err := authorization.Export(primus.TokenFormats.Armor)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitFailure
+	}
+	if err := writeOutput(e, *output, armored); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	fmt.Fprintln(e.stderr, "approved")
+	return exitOK
+}
+
+func writeApprovalSummary(e *env, token *primus.ApprovalToken, summary *approvalReport, opts *approveOptions) {
+	t := &textWriter{w: e.stderr}
+	t.section("approval token", func() {
+		writeApprovalText(t, summary)
+		if text, ok := printable(token.EkaPayload); ok {
+			t.field("payload text", fmt.Sprintf("%q", text))
+		}
+		if issuedAt, ok := token.Time(); ok {
+			t.field("age", opts.now().Sub(issuedAt).Round(time.Second))
+		}
+		if token.Operation == primus.ApprovalTokenOp.MODIFY {
+			access := new(primus.Access)
+			if err := access.Deserialize(token.EkaPayload); err != nil {
+				t.field("new policy", "undecodable: "+err.Error())
+				return
+			}
+			t.section("new policy", func() { writeAccessText(t, access) })
+		}
+	})
+	if summary.TimestampSignature != nil && summary.TimestampSignature.Status == statusUnchecked {
+		fmt.Fprintln(e.stderr, "warning: timestamp not verified, pass -integrity-key to check it")
+	}
+}
+
+// approvalParts are the parts of an approval token, each may occur once.
+var approvalParts = []primus.PayloadType{primus.EKA_OPERATION, primus.LABEL_UTF8STRING, primus.EKA_TIME_STAMP,
+	primus.DER_SIGNATURE, primus.EKA_SIGN_PAYLOAD, primus.EKA_MODIFY_PAYLOAD}
+
+// checkApprovalParts rejects approval tokens which ApprovalToken.Deserialize would read
+// leniently, so the summary shows what the HSM executes: repeated or unknown parts and tokens
+// with both a sign and a modify payload.
+func checkApprovalParts(approvalBytes []byte) error {
+	payload, err := primus.DecodePayload(approvalBytes)
+	if err != nil {
+		return err
+	}
+	seen := make(map[primus.PayloadType]bool)
+	for _, part := range payload.Parts() {
+		typ := part.Type()
+		if !slices.Contains(approvalParts, typ) {
+			return fmt.Errorf("unexpected part %s", typ)
+		}
+		if seen[typ] {
+			return fmt.Errorf("repeated part %s", typ)
+		}
+		seen[typ] = true
+	}
+	if seen[primus.EKA_SIGN_PAYLOAD] && seen[primus.EKA_MODIFY_PAYLOAD] {
+		return fmt.Errorf("both %s and %s", primus.EKA_SIGN_PAYLOAD, primus.EKA_MODIFY_PAYLOAD)
+	}
+	return nil
+}
+
+// checkApproval refuses tokens whose timestamp doesn't verify, covers another payload or is
+// too old.
+func checkApproval(token *primus.ApprovalToken, summary *approvalReport, opts *approveOptions) error {
+	if summary.TimestampSignature != nil && summary.TimestampSignature.Status == statusInvalid {
+		return fmt.Errorf("timestamp signature: %s", summary.TimestampSignature.Error)
+	}
+	if summary.Timestamp != nil && summary.Timestamp.Payload != summary.Payload {
+		return errors.New("timestamp covers another payload")
+	}
+	if opts.integrityKey != nil && summary.TimestampSignature == nil {
+		return primus.ErrMissingTimestamp
+	}
+	if opts.maxAge > 0 {
+		issuedAt, ok := token.Time()
+		if !ok {
+			return primus.ErrMissingTimestamp
+		}
+		if opts.now().Sub(issuedAt) > opts.maxAge {
+			return primus.ErrTokenExpired
+		}
+	}
+	return nil
+}
+
+// checkAuthorization verifies an authorization token, its signer must be the keystore key and
+// it must approve approvalBytes if given.
+func checkAuthorization(data, approvalBytes []byte, opts *approveOptions) (*primus.VerifiedAuthorization, error) {
+	verified, err := primus.VerifyAuthorizationToken(data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.keystore != nil {
+		publicKey, err := opts.keystore.ParsedPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if !verified.Key.Equal(publicKey) {
+			return nil, fmt.Errorf("signed by %s, not by the keystore key %s", verified.Key.Fingerprint(), publicKey.Fingerprint())
+		}
+	}
+	if approvalBytes != nil && !bytes.Equal(verified.ApprovalTokenBytes, approvalBytes) {
+		return nil, errors.New("approves another approval token")
+	}
+	return verified, nil
+}
+
+func verifyApproval(e *env, opts *approveOptions, authorizationArg, approvalArg string) int {
+	data, err := readInput(e, authorizationArg)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	authorization, err := primus.ImportAuthorizationToken(data)
+	if err != nil {
+		e.errorf("authorization token: %v", err)
+		return exitUsage
+	}
+	var approvalBytes []byte
+	if approvalArg != "" {
+		data, err := readInput(e, approvalArg)
+		if err == nil {
+			_, approvalBytes, err = primus.ImportApprovalToken(data)
+		}
+		if err != nil {
+			e.errorf("approval token: %v", err)
+			return exitUsage
+		}
+	}
+	verified, err := checkAuthorization(authorization.GetEncoding(), approvalBytes, opts)
+	if err != nil {
+		fmt.Fprintf(e.stdout, "invalid: %v\n", err)
+		return exitFailure
+	}
+	if err := checkApprovalParts(verified.ApprovalTokenBytes); err != nil {
+		fmt.Fprintf(e.stdout, "invalid: approval token: %v\n", err)
+		return exitFailure
+	}
+	summary := inspectApproval(verified.ApprovalToken, &inspectOptions{integrityKey: opts.integrityKey})
+	if err := checkApproval(verified.ApprovalToken, summary, opts); err != nil {
+		fmt.Fprintf(e.stdout, "invalid: %v\n", err)
+		return exitFailure
+	}
+	t := &textWriter{w: e.stdout}
+	t.field("signature", statusValid)
+	t.field("algorithm", verified.Algorithm)
+	t.field("signer", verified.Key.Fingerprint())
+	if opts.keystore != nil {
+		t.field("keystore", opts.keystore.Label)
+	}
+	t.section("approval token", func() { writeApprovalText(t, summary) })
+	if t.err != nil {
+		e.errorf("%v", t.err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// readPassphrase reads the passphrase from path, from the terminal without echo, or else a line
+// of stdin.
+func readPassphrase(e *env, in *bufio.Reader, path string) ([]byte, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+	fmt.Fprint(e.stderr, "passphrase: ")
+	defer fmt.Fprintln(e.stderr)
+	if f, ok := e.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) && in.Buffered() == 0 {
+		return term.ReadPassword(int(f.Fd()))
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, errors.New("no passphrase")
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+func printable(bs []byte) (string, bool) {
+	if len(bs) == 0 || !utf8.Valid(bs) {
+		return "", false
+	}
+	for _, r := range string(bs) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return string(bs), true
+}
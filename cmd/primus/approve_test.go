@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/donutnomad/primus"
+	"github.com/samber/lo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApprove(t *testing.T) {
+	dir := t.TempDir()
+	approverKey, integrityKey := newTestKey(), newTestKey()
+	keystore := lo.Must(primus.NewKeystore("alice", approverKey, []byte("secret"), &primus.KeystoreOptions{ScryptN: 1 << 10}))
+	keystorePath := filepath.Join(dir, "alice.keystore")
+	lo.Must0(keystore.Save(keystorePath))
+	approvalPath := filepath.Join(dir, "approval.asc")
+	lo.Must0(os.WriteFile(approvalPath, lo.Must(newTestApprovalToken(integrityKey).Export(primus.TokenFormats.Armor)), 0644))
+	integrityKeyPath := writeTestPublicKey(t, integrityKey)
+
+	code, stdout, stderr := runCommand(t, "no\n", "approve", "-keystore", keystorePath, approvalPath)
+	if code != exitFailure || stdout != "" || !strings.Contains(stderr, "not approved") {
+		t.Fatal("expected refusal", code, stderr)
+	}
+	for _, expected := range []string{"operation: SIGN", `key name: "gt_ec_08"`, `payload text: "content to be sign"`, "signature: unchecked", "warning: timestamp not verified"} {
+		if !strings.Contains(stderr, expected) {
+			t.Fatal("summary misses", expected, stderr)
+		}
+	}
+	if code, _, stderr = runCommand(t, "yes\nsecret\n", "approve", "-keystore", keystorePath, "-integrity-key", writeTestPublicKey(t, approverKey), approvalPath); code != exitFailure || !strings.Contains(stderr, "refusing to approve") {
+		t.Fatal("expected invalid timestamp", code, stderr)
+	}
+	if code, _, stderr = runCommand(t, "yes\nwrong\n", "approve", "-keystore", keystorePath, approvalPath); code != exitFailure || !strings.Contains(stderr, primus.ErrKeystorePassphrase.Error()) {
+		t.Fatal("expected wrong passphrase", code, stderr)
+	}
+
+	code, stdout, stderr = runCommand(t, "yes\nsecret\n", "approve", "-keystore", keystorePath, "-integrity-key", integrityKeyPath, approvalPath)
+	if code != exitOK || !strings.HasPrefix(stdout, "-----BEGIN "+primus.ArmorTypes.AuthorizationToken) {
+		t.Fatal("approval failed", code, stderr)
+	}
+	if !strings.Contains(stderr, "signature: valid") || !strings.Contains(stderr, "with SHA256withECDSA") {
+		t.Fatal("unexpected summary", stderr)
+	}
+	authorizationPath := filepath.Join(dir, "authorization.asc")
+	lo.Must0(os.WriteFile(authorizationPath, []byte(stdout), 0644))
+
+	code, stdout, stderr = runCommand(t, "", "approve", "-verify", "-keystore", keystorePath, "-integrity-key", integrityKeyPath, authorizationPath, approvalPath)
+	if code != exitOK || !strings.Contains(stdout, "signature: valid") || !strings.Contains(stdout, "keystore: alice") {
+		t.Fatal("verification failed", code, stdout, stderr)
+	}
+
+	other := filepath.Join(dir, "other.keystore")
+	lo.Must0(lo.Must(primus.NewKeystore("bob", newTestKey(), []byte("secret"), &primus.KeystoreOptions{ScryptN: 1 << 10})).Save(other))
+	if code, stdout, _ = runCommand(t, "", "approve", "-verify", "-keystore", other, authorizationPath); code != exitFailure || !strings.Contains(stdout, "not by the keystore key") {
+		t.Fatal("expected signer mismatch", code, stdout)
+	}
+	otherApproval := lo.Must(primus.NewPrimusApprovalToken(primus.ApprovalTokenOp.BLOCK, nil, "gt_ec_08").Export(primus.TokenFormats.Hex))
+	if code, stdout, _ = runCommand(t, "", "approve", "-verify", authorizationPath, string(otherApproval)); code != exitFailure || !strings.Contains(stdout, "approves another approval token") {
+		t.Fatal("expected approval mismatch", code, stdout)
+	}
+
+	// parts the summary wouldn't show are refused before it
+	approvalBytes := newTestApprovalToken(integrityKey).Serialize()
+	for _, part := range []struct {
+		typ      primus.PayloadType
+		data     string
+		expected string
+	}{
+		{primus.LABEL_UTF8STRING, "other key", "repeated part LABEL_UTF8STRING"},
+		{4000, "", "unexpected part 4000"},
+		{primus.EKA_MODIFY_PAYLOAD, "policy", "both EKA_SIGN_PAYLOAD and EKA_MODIFY_PAYLOAD"},
+	} {
+		malformed := hex.EncodeToString(appendTestPart(approvalBytes, part.typ, []byte(part.data)))
+		if code, _, stderr = runCommand(t, "yes\nsecret\n", "approve", "-keystore", keystorePath, malformed); code != exitUsage || !strings.Contains(stderr, part.expected) {
+			t.Fatal("expected", part.expected, code, stderr)
+		}
+	}
+	// a timestamp issued for another payload
+	timestamp := primus.EncodePrimusTimestamp([]byte("other content"), "global-integrity-key", time.Now().Unix())
+	sig := lo.Must(primus.FindEcdsaByName(primus.EcdsaSignAlg.SHA256withECDSA).Sign(integrityKey, timestamp))
+	swapped := lo.Must(primus.NewPrimusApprovalTokenWithTime(primus.ApprovalTokenOp.SIGN, []byte("content to be sign"), "gt_ec_08", timestamp,
+		primus.NewPrimusSignature(primus.EcdsaSignAlg.SHA256withECDSA, sig)).Export(primus.TokenFormats.Hex))
+	if code, _, stderr = runCommand(t, "yes\nsecret\n", "approve", "-keystore", keystorePath, "-integrity-key", integrityKeyPath, string(swapped)); code != exitFailure || !strings.Contains(stderr, "timestamp covers another payload") {
+		t.Fatal("expected payload mismatch", code, stderr)
+	}
+
+	// scripted use with the token on stdin
+	passphrase := filepath.Join(dir, "passphrase")
+	lo.Must0(os.WriteFile(passphrase, []byte("secret\n"), 0600))
+	approval := lo.Must(os.ReadFile(approvalPath))
+	if code, _, _ = runCommand(t, string(approval), "approve", "-keystore", keystorePath, approvalPath, "-"); code != exitUsage {
+		t.Fatal("expected usage error", code)
+	}
+	if code, _, stderr = runCommand(t, string(approval), "approve", "-keystore", keystorePath, "-"); code != exitUsage {
+		t.Fatal("expected confirmation to need stdin", code, stderr)
+	}
+	if code, stdout, stderr = runCommand(t, string(approval), "approve", "-keystore", keystorePath, "-yes", "-passphrase-file", passphrase, "-alg", "SHA384withECDSA", "-"); code != exitOK {
+		t.Fatal("approval failed", code, stderr)
+	}
+	verified := lo.Must(primus.VerifyAuthorizationToken(lo.Must(primus.ImportAuthorizationToken([]byte(stdout))).GetEncoding(), nil))
+	if verified.Algorithm != primus.EcdsaSignAlg.SHA384withECDSA {
+		t.Fatal("unexpected algorithm", verified.Algorithm)
+	}
+}
+
+// appendTestPart appends a part of type typ to the length-headed payload bs.
+func appendTestPart(bs []byte, typ primus.PayloadType, data []byte) []byte {
+	out := binary.LittleEndian.AppendUint16(append([]byte(nil), bs[4:]...), uint16(typ))
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(data)))
+	out = append(out, data...)
+	out = append(out, make([]byte, (4-len(data)%4)%4)...)
+	return append(primus.LEUint32(len(out)), out...)
+}
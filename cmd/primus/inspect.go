@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/donutnomad/primus"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var inspectKinds = struct {
+	AuthorizationToken string
+	ApprovalToken      string
+	Access             string
+	Timestamp          string
+	Payload            string
+}{
+	AuthorizationToken: "authorization-token",
+	ApprovalToken:      "approval-token",
+	Access:             "access",
+	Timestamp:          "timestamp",
+	Payload:            "payload",
+}
+
+// Verification status of a signature.
+const (
+	statusValid     = "valid"
+	statusInvalid   = "invalid"
+	statusUnchecked = "unchecked"
+)
+
+type verification struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func verified(err error) *verification {
+	if err != nil {
+		return &verification{Status: statusInvalid, Error: err.Error()}
+	}
+	return &verification{Status: statusValid}
+}
+
+type partReport struct {
+	Type   string `json:"type"`
+	Length int    `json:"length"`
+	Data   string `json:"data"`
+}
+
+type timestampReport struct {
+	KeyName string    `json:"key_name"`
+	Time    time.Time `json:"time"`
+	Payload string    `json:"payload,omitempty"`
+}
+
+type approvalReport struct {
+	Operation          string           `json:"operation"`
+	KeyName            string           `json:"key_name"`
+	Payload            string           `json:"payload,omitempty"`
+	Timestamp          *timestampReport `json:"timestamp,omitempty"`
+	TimestampAlgorithm string           `json:"timestamp_algorithm,omitempty"`
+	TimestampSignature *verification    `json:"timestamp_signature,omitempty"`
+}
+
+type authorizationReport struct {
+	Algorithm    string          `json:"algorithm"`
+	Signer       string          `json:"signer"`
+	KeyAlgorithm string          `json:"key_algorithm,omitempty"`
+	Identity     string          `json:"identity,omitempty"`
+	Certificates []string        `json:"certificates,omitempty"`
+	Approval     *approvalReport `json:"approval"`
+	Signature    *verification   `json:"signature"`
+}
+
+type inspectReport struct {
+	Kind          string               `json:"kind"`
+	Format        string               `json:"format"`
+	Headers       map[string]string    `json:"unverified_headers,omitempty"`
+	Authorization *authorizationReport `json:"authorization,omitempty"`
+	Approval      *approvalReport      `json:"approval,omitempty"`
+	Timestamp     *timestampReport     `json:"timestamp,omitempty"`
+	Access        *primus.Access       `json:"access,omitempty"`
+	Parts         []partReport         `json:"parts,omitempty"`
+}
+
+// failed reports whether a signature of the report didn't verify.
+func (r *inspectReport) failed() bool {
+	approval := r.Approval
+	if r.Authorization != nil {
+		if r.Authorization.Signature.Status == statusInvalid {
+			return true
+		}
+		approval = r.Authorization.Approval
+	}
+	return approval != nil && approval.TimestampSignature != nil && approval.TimestampSignature.Status == statusInvalid
+}
+
+type inspectOptions struct {
+	keyring      *primus.Keyring
+	integrityKey *primus.ParsedPublicKey
+}
+
+func runInspect(e *env, args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(e.stderr, "usage: primus inspect [flags] [file | - | data]")
+		fmt.Fprintln(e.stderr, "\nReads hex, base64, armored or raw input and exits with 1 if a signature doesn't verify.")
+		fs.PrintDefaults()
+	}
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	keyringPath := fs.String("keyring", "", "keyring file or directory the signer must belong to")
+	integrityKeyPath := fs.String("integrity-key", "", "public key of the HSM integrity key, to verify timestamps")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	var opts inspectOptions
+	var err error
+	if *keyringPath != "" {
+		if opts.keyring, err = primus.LoadKeyring(*keyringPath); err != nil {
+			e.errorf("%v", err)
+			return exitUsage
+		}
+	}
+	if *integrityKeyPath != "" {
+		if opts.integrityKey, err = loadPublicKey(*integrityKeyPath); err != nil {
+			e.errorf("integrity key: %v", err)
+			return exitUsage
+		}
+	}
+	data, err := readInput(e, fs.Arg(0))
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	report, err := inspect(data, &opts)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	if *jsonOutput {
+		err = writeJSON(e.stdout, report)
+	} else {
+		err = writeInspectText(e.stdout, report)
+	}
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	if report.failed() {
+		return exitFailure
+	}
+	return exitOK
+}
+
+func loadPublicKey(path string) (*primus.ParsedPublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return primus.ImportPublicKey("", data)
+}
+
+func inspect(data []byte, opts *inspectOptions) (*inspectReport, error) {
+	text, err := primus.DecodeTokenText(data)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := primus.DecodePayload(text.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	report := &inspectReport{Format: text.Format, Headers: text.Headers}
+	switch {
+	case payload.Find(primus.APPROVAL_TOKEN) != nil:
+		report.Kind = inspectKinds.AuthorizationToken
+		report.Authorization, err = inspectAuthorization(text.Bytes, opts)
+	case payload.Find(primus.EKA_OPERATION) != nil:
+		report.Kind = inspectKinds.ApprovalToken
+		token := new(primus.ApprovalToken)
+		if err = token.Deserialize(text.Bytes); err == nil {
+			report.Approval = inspectApproval(token, opts)
+		}
+	case payload.Find(primus.TIME_SECONDS_SINCE_EPOCH) != nil:
+		report.Kind = inspectKinds.Timestamp
+		report.Timestamp = inspectTimestamp(text.Bytes)
+	case isAccess(payload):
+		report.Kind = inspectKinds.Access
+		report.Access = new(primus.Access)
+		err = report.Access.Deserialize(text.Bytes)
+	default:
+		report.Kind = inspectKinds.Payload
+		for _, part := range payload.Parts() {
+			report.Parts = append(report.Parts, partReport{
+				Type:   part.Type().String(),
+				Length: len(part.Data()),
+				Data:   hex.EncodeToString(part.Data()),
+			})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", report.Kind, err)
+	}
+	return report, nil
+}
+
+func isAccess(payload *primus.Payload) bool {
+	parts := payload.Parts()
+	return len(parts) > 0 && parts[0].Type() == primus.SIGN_BLOB
+}
+
+func inspectAuthorization(data []byte, opts *inspectOptions) (*authorizationReport, error) {
+	token, err := primus.NewPrimusAuthorizationTokenImpl(data)
+	if err != nil {
+		return nil, err
+	}
+	report := &authorizationReport{
+		Algorithm: primus.ExtractSignAlgorithm(token.DerSignatureBytes).String(),
+		Approval:  inspectApproval(token.ApprovalToken, opts),
+	}
+	if key, err := primus.NewParsedPublicKey("", token.PublicKeyEncodedBytes); err == nil {
+		report.Signer = key.Fingerprint().String()
+		report.KeyAlgorithm = key.KeyAlg().String()
+	} else {
+		report.Signer = hex.EncodeToString(token.PublicKeyEncodedBytes)
+	}
+	for _, cert := range token.Certificates {
+		report.Certificates = append(report.Certificates, cert.Subject.String())
+	}
+	result, err := token.Verify(&primus.VerifyOptions{Keyring: opts.keyring})
+	report.Signature = verified(err)
+	if err == nil {
+		report.Identity = result.Identity
+	}
+	return report, nil
+}
+
+func inspectApproval(token *primus.ApprovalToken, opts *inspectOptions) *approvalReport {
+	report := &approvalReport{
+		Operation: token.Operation.String(),
+		KeyName:   token.KeyName,
+		Payload:   hex.EncodeToString(token.EkaPayload),
+	}
+	if len(token.Timestamp) > 0 {
+		report.Timestamp = inspectTimestamp(token.Timestamp)
+		report.TimestampSignature = &verification{Status: statusUnchecked}
+		if token.TimestampSignature != nil {
+			report.TimestampAlgorithm = token.TimestampSignature.SignAlgorithm().String()
+		}
+		if opts.integrityKey != nil {
+			report.TimestampSignature = verified(token.VerifyTimestamp(opts.integrityKey.PublicKey()))
+		}
+	}
+	return report
+}
+
+func inspectTimestamp(timestamp []byte) *timestampReport {
+	keyName, seconds := primus.DecodePrimusTimestamp(timestamp)
+	report := &timestampReport{KeyName: keyName, Time: time.Unix(seconds, 0).UTC()}
+	if payload, err := primus.DecodePayload(timestamp); err == nil {
+		if part := payload.Find(primus.EKA_SIGN_PAYLOAD); part != nil {
+			report.Payload = hex.EncodeToString(part.Data())
+		}
+	}
+	return report
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// textWriter prints indented "key: value" lines.
+type textWriter struct {
+	w      io.Writer
+	indent int
+	err    error
+}
+
+func (t *textWriter) line(format string, args ...any) {
+	if t.err == nil {
+		_, t.err = fmt.Fprintf(t.w, strings.Repeat("  ", t.indent)+format+"\n", args...)
+	}
+}
+
+func (t *textWriter) field(key string, value any) {
+	t.line("%s: %v", key, value)
+}
+
+func (t *textWriter) section(key string, fn func()) {
+	t.line("%s:", key)
+	t.indent++
+	fn()
+	t.indent--
+}
+
+func (t *textWriter) verification(key string, v *verification) {
+	if v.Error != "" {
+		t.field(key, v.Status+" ("+v.Error+")")
+	} else {
+		t.field(key, v.Status)
+	}
+}
+
+func writeInspectText(w io.Writer, r *inspectReport) error {
+	t := &textWriter{w: w}
+	t.field("kind", r.Kind)
+	t.field("format", r.Format)
+	if len(r.Headers) > 0 {
+		t.section("armor headers (unverified)", func() {
+			keys := make([]string, 0, len(r.Headers))
+			for k := range r.Headers {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				t.line("%q: %q", k, r.Headers[k])
+			}
+		})
+	}
+	switch {
+	case r.Authorization != nil:
+		a := r.Authorization
+		t.field("algorithm", a.Algorithm)
+		t.field("signer", a.Signer)
+		if a.KeyAlgorithm != "" {
+			t.field("key algorithm", a.KeyAlgorithm)
+		}
+		if a.Identity != "" {
+			t.field("identity", a.Identity)
+		}
+		for _, subject := range a.Certificates {
+			t.field("certificate", subject)
+		}
+		t.verification("signature", a.Signature)
+		t.section("approval token", func() { writeApprovalText(t, a.Approval) })
+	case r.Approval != nil:
+		writeApprovalText(t, r.Approval)
+	case r.Timestamp != nil:
+		writeTimestampText(t, r.Timestamp)
+	case r.Access != nil:
+		writeAccessText(t, r.Access)
+	default:
+		for _, part := range r.Parts {
+			t.field(part.Type, fmt.Sprintf("(%d bytes) %s", part.Length, part.Data))
+		}
+	}
+	return t.err
+}
+
+func writeApprovalText(t *textWriter, a *approvalReport) {
+	t.field("operation", a.Operation)
+	t.field("key name", fmt.Sprintf("%q", a.KeyName))
+	t.field("payload", a.Payload)
+	if a.Timestamp != nil {
+		t.section("timestamp", func() {
+			writeTimestampText(t, a.Timestamp)
+			if a.TimestampAlgorithm != "" {
+				t.field("algorithm", a.TimestampAlgorithm)
+			}
+			t.verification("signature", a.TimestampSignature)
+		})
+	}
+}
+
+func writeTimestampText(t *textWriter, ts *timestampReport) {
+	t.field("time", ts.Time.Format(time.RFC3339))
+	t.field("integrity key", fmt.Sprintf("%q", ts.KeyName))
+	if ts.Payload != "" {
+		t.field("payload", ts.Payload)
+	}
+}
+
+func writeAccessText(t *textWriter, a *primus.Access) {
+	names := []primus.BlobName{primus.BlobNames.Signing, primus.BlobNames.Block, primus.BlobNames.UnBlock, primus.BlobNames.Modify}
+	for i, blob := range a.Blobs() {
+		t.section(names[i].String(), func() {
+			for _, token := range blob {
+				t.section(fmt.Sprintf("token %q", token.Name), func() {
+					t.field("delay", time.Duration(token.DelayMs)*time.Millisecond)
+					t.field("time limit", time.Duration(token.TimeLimitMs)*time.Millisecond)
+					for _, group := range token.Groups {
+						t.section(fmt.Sprintf("group %q (%d of %d)", group.Name, group.Quorum, len(group.PublicKeys)), func() {
+							for _, publicKey := range group.PublicKeys {
+								t.field("key", describeKey(publicKey))
+							}
+						})
+					}
+				})
+			}
+		})
+	}
+}
+
+func describeKey(publicKey primus.Publickey) string {
+	key, err := primus.ParsePublicKey(publicKey)
+	if err != nil {
+		return hex.EncodeToString(publicKey.GetEncoded())
+	}
+	desc := key.Fingerprint().String()
+	if alg := key.KeyAlg(); alg != "" {
+		desc += " " + alg.String()
+	}
+	if name := key.GetName(); name != "" {
+		desc = name + " " + desc
+	}
+	return desc
+}
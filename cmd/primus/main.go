@@ -0,0 +1,99 @@
+// Command primus inspects and manipulates Primus HSM payloads, tokens and policies.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// env carries the standard streams, so commands can run in tests.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (e *env) errorf(format string, args ...any) {
+	fmt.Fprintf(e.stderr, "primus: "+format+"\n", args...)
+}
+
+// Exit codes shared by the commands.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the input was read but didn't pass a check
+	exitUsage   = 2 // bad arguments or unreadable input
+)
+
+type command struct {
+	summary string
+	run     func(e *env, args []string) int
+}
+
+var commands = map[string]command{
+	"inspect": {"decode a token, policy or payload and verify its signatures", runInspect},
+	"policy":  {"build, decode, diff and lint access policies", runPolicy},
+	"approve": {"review an approval token and sign it with a keystore key", runApprove},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	return dispatch(e, "primus", commands, args)
+}
+
+// dispatch runs the command named by args[0].
+func dispatch(e *env, prefix string, cmds map[string]command, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr, prefix, cmds)
+		return exitUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(e.stdout, prefix, cmds)
+		return exitOK
+	}
+	cmd, ok := cmds[args[0]]
+	if !ok {
+		e.errorf("unknown command %q", args[0])
+		usage(e.stderr, prefix, cmds)
+		return exitUsage
+	}
+	return cmd.run(e, args[1:])
+}
+
+func usage(w io.Writer, prefix string, cmds map[string]command) {
+	fmt.Fprintf(w, "usage: %s <command> [flags] [args]\n", prefix)
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-16s%s\n", name, cmds[name].summary)
+	}
+}
+
+// readInput reads arg as a file, stdin for "" and "-", or else takes arg itself as the data.
+func readInput(e *env, arg string) ([]byte, error) {
+	if arg == "" || arg == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	if _, err := os.Stat(arg); err != nil {
+		return []byte(arg), nil
+	}
+	return os.ReadFile(arg)
+}
+
+// writeOutput writes data to path, or to stdout if path is empty.
+func writeOutput(e *env, path string, data []byte) error {
+	if path == "" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
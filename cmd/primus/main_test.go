@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/donutnomad/primus"
+	"github.com/samber/lo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newTestKey() *ecdsa.PrivateKey {
+	return lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+}
+
+func writeTestPublicKey(t *testing.T, key *ecdsa.PrivateKey) string {
+	parsed := lo.Must(primus.NewParsedPublicKeyFromKey("", key.Public()))
+	path := filepath.Join(t.TempDir(), "key.pem")
+	lo.Must0(os.WriteFile(path, lo.Must(parsed.Export(primus.PublicKeyFormats.PEM)), 0o644))
+	return path
+}
+
+// newTestApprovalToken returns an approval token timestamped by integrityKey, as issued by the HSM.
+func newTestApprovalToken(integrityKey *ecdsa.PrivateKey) *primus.ApprovalToken {
+	payload := []byte("content to be sign")
+	timestamp := primus.EncodePrimusTimestamp(payload, "global-integrity-key", time.Now().Unix())
+	sig := lo.Must(primus.FindEcdsaByName(primus.EcdsaSignAlg.SHA256withECDSA).Sign(integrityKey, timestamp))
+	return primus.NewPrimusApprovalTokenWithTime(primus.ApprovalTokenOp.SIGN, payload, "gt_ec_08", timestamp,
+		primus.NewPrimusSignature(primus.EcdsaSignAlg.SHA256withECDSA, sig))
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, _ := runCommand(t, ""); code != exitUsage {
+		t.Fatal("expected usage error, got", code)
+	}
+	if code, _, stderr := runCommand(t, "", "nope"); code != exitUsage || !strings.Contains(stderr, "unknown command") {
+		t.Fatal("unexpected result", code, stderr)
+	}
+}
+
+func TestInspectAuthorizationToken(t *testing.T) {
+	integrityKey, approverKey := newTestKey(), newTestKey()
+	token := lo.Must(newTestApprovalToken(integrityKey).Authorize(approverKey, primus.EcdsaSignAlg.SHA256withECDSA))
+	encoded := hex.EncodeToString(token.GetEncoding())
+
+	code, stdout, stderr := runCommand(t, "", "inspect", "--json", "--integrity-key", writeTestPublicKey(t, integrityKey), encoded)
+	if code != exitOK {
+		t.Fatal(code, stderr)
+	}
+	var report inspectReport
+	lo.Must0(json.Unmarshal([]byte(stdout), &report))
+	if report.Kind != inspectKinds.AuthorizationToken || report.Format != primus.TokenFormats.Hex {
+		t.Fatal("unexpected kind", report.Kind, report.Format)
+	}
+	a := report.Authorization
+	if a.Signature.Status != statusValid || a.Algorithm != "SHA256withECDSA" || a.KeyAlgorithm != primus.KeyAlg.SECP256R1.String() {
+		t.Fatal("unexpected authorization", stdout)
+	}
+	if a.Approval.Operation != "SIGN" || a.Approval.KeyName != "gt_ec_08" || a.Approval.TimestampSignature.Status != statusValid {
+		t.Fatal("unexpected approval", stdout)
+	}
+	if a.Approval.Timestamp.KeyName != "global-integrity-key" {
+		t.Fatal("unexpected timestamp", stdout)
+	}
+
+	// timestamp signed by another key
+	code, stdout, _ = runCommand(t, "", "inspect", "--integrity-key", writeTestPublicKey(t, approverKey), encoded)
+	if code != exitFailure || !strings.Contains(stdout, "signature: invalid") {
+		t.Fatal("expected invalid timestamp signature", code, stdout)
+	}
+
+	// stdin, armored
+	armored := lo.Must(token.Export(primus.TokenFormats.Armor))
+	code, stdout, stderr = runCommand(t, string(armored), "inspect")
+	if code != exitOK || !strings.Contains(stdout, "kind: authorization-token") || !strings.Contains(stdout, "signature: valid") {
+		t.Fatal("unexpected output", code, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "armor headers (unverified):") || !strings.Contains(stdout, `"Key-Name": "gt_ec_08"`) {
+		t.Fatal("armor headers must be quoted and marked unverified", stdout)
+	}
+}
+
+func TestInspectInvalidSignature(t *testing.T) {
+	key := newTestKey()
+	approval := primus.NewPrimusApprovalToken(primus.ApprovalTokenOp.SIGN, []byte("payload"), "key")
+	other := primus.NewPrimusApprovalToken(primus.ApprovalTokenOp.SIGN, []byte("other"), "key")
+	sig := lo.Must(primus.FindEcdsaByName(primus.EcdsaSignAlg.SHA256withECDSA).Sign(key, other.Serialize()))
+	publicKey := lo.Must(primus.NewParsedPublicKeyFromKey("", key.Public())).GetEncoded()
+	token := primus.NewPrimusAuthorizationTokenEncode(approval.Serialize(), sig, primus.EcdsaSignAlg.SHA256withECDSA, publicKey, false)
+
+	code, stdout, _ := runCommand(t, "", "inspect", hex.EncodeToString(token.GetEncoding()))
+	if code != exitFailure || !strings.Contains(stdout, "signature: invalid") {
+		t.Fatal("expected invalid signature", code, stdout)
+	}
+}
+
+func TestInspectKinds(t *testing.T) {
+	approval := newTestApprovalToken(newTestKey())
+	group := &primus.AccessGroup{Name: "g", Quorum: 1, PublicKeys: []primus.Publickey{
+		lo.Must(primus.NewParsedPublicKeyFromKey("", newTestKey().Public())),
+	}}
+	token := &primus.AccessToken{Name: "t", TimeLimitMs: 60000, Groups: []*primus.AccessGroup{group}}
+	access := primus.NewAccess([]*primus.AccessToken{token}, []*primus.AccessToken{token}, []*primus.AccessToken{token}, []*primus.AccessToken{token})
+
+	for _, item := range []struct {
+		data []byte
+		kind string
+	}{
+		{approval.Serialize(), inspectKinds.ApprovalToken},
+		{approval.Timestamp, inspectKinds.Timestamp},
+		{access.Serialize(), inspectKinds.Access},
+		{access.ToModifyPayload(), inspectKinds.Access},
+		{primus.NewPayloadPart(primus.LABEL_UTF8STRING, []byte("label")).Serialize(), inspectKinds.Payload},
+	} {
+		code, stdout, stderr := runCommand(t, "", "inspect", "--json", hex.EncodeToString(item.data))
+		if code != exitOK {
+			t.Fatal(item.kind, code, stderr)
+		}
+		var report struct{ Kind string }
+		lo.Must0(json.Unmarshal([]byte(stdout), &report))
+		if report.Kind != item.kind {
+			t.Fatal("expected", item.kind, "got", report.Kind)
+		}
+	}
+
+	code, stdout, _ := runCommand(t, "", "inspect", hex.EncodeToString(primus.NewPayloadPart(primus.LABEL_UTF8STRING, []byte("label")).Serialize()))
+	if code != exitOK || !strings.Contains(stdout, "LABEL_UTF8STRING: (5 bytes) 6c6162656c") {
+		t.Fatal("unexpected output", stdout)
+	}
+	code, stdout, _ = runCommand(t, "", "inspect", hex.EncodeToString(access.Serialize()))
+	if code != exitOK || !strings.Contains(stdout, `group "g" (1 of 1)`) {
+		t.Fatal("unexpected output", stdout)
+	}
+}
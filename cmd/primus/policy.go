@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/donutnomad/primus"
+)
+
+var policyCommands = map[string]command{
+	"build":          {"encode a policy file as Access blob", runPolicyBuild},
+	"decode":         {"print an Access blob as policy file", runPolicyDecode},
+	"diff":           {"compare two policies, exits with 1 if the new one is weaker", runPolicyDiff},
+	"lint":           {"check a policy, exits with 1 on findings of --fail-on severity", runPolicyLint},
+	"modify-payload": {"encode a policy as payload of a MODIFY request", runPolicyModifyPayload},
+}
+
+func runPolicy(e *env, args []string) int {
+	return dispatch(e, "primus policy", policyCommands, args)
+}
+
+// policyFlags are the flags shared by the policy commands.
+type policyFlags struct {
+	*flag.FlagSet
+	keyring string
+}
+
+func newPolicyFlags(e *env, name, args string) *policyFlags {
+	fs := &policyFlags{FlagSet: flag.NewFlagSet(name, flag.ContinueOnError)}
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: primus policy %s [flags] %s\n", name, args)
+		fmt.Fprintln(e.stderr, "\nPolicies are JSON files or Access blobs in hex, base64, armor or raw form.")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&fs.keyring, "keyring", "", "keyring file or directory, to refer to keys by name and name them")
+	return fs
+}
+
+// parse parses args, which must leave minArgs to maxArgs arguments.
+func (fs *policyFlags) parse(args []string, minArgs, maxArgs int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() < minArgs || fs.NArg() > maxArgs {
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+func (fs *policyFlags) loadKeyring() (*primus.Keyring, error) {
+	if fs.keyring == "" {
+		return nil, nil
+	}
+	return primus.LoadKeyring(fs.keyring)
+}
+
+// loadPolicy reads a policy file or an encoded Access.
+func loadPolicy(e *env, arg string, keyring *primus.Keyring) (*primus.Access, error) {
+	data, err := readInput(e, arg)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return primus.ParsePolicy(data, keyring)
+	}
+	text, err := primus.DecodeTokenText(data)
+	if err != nil {
+		return nil, err
+	}
+	access := new(primus.Access)
+	if err := access.Deserialize(text.Bytes); err != nil {
+		return nil, fmt.Errorf("access: %w", err)
+	}
+	return access, nil
+}
+
+func runPolicyBuild(e *env, args []string) int {
+	return encodePolicy(e, "build", args, (*primus.Access).Serialize)
+}
+
+func runPolicyModifyPayload(e *env, args []string) int {
+	return encodePolicy(e, "modify-payload", args, (*primus.Access).ToModifyPayload)
+}
+
+func encodePolicy(e *env, name string, args []string, encode func(*primus.Access) []byte) int {
+	fs := newPolicyFlags(e, name, "policy")
+	format := fs.String("format", primus.TokenFormats.Hex, "output format: raw, hex, base64, base64url or armor")
+	output := fs.String("o", "", "output file, defaults to stdout")
+	if !fs.parse(args, 1, 1) {
+		return exitUsage
+	}
+	keyring, err := fs.loadKeyring()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	access, err := loadPolicy(e, fs.Arg(0), keyring)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	text := &primus.TokenText{Format: *format, Type: primus.ArmorTypes.Access, Bytes: encode(access)}
+	data, err := text.Encode()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	if *format != primus.TokenFormats.Raw && *format != primus.TokenFormats.Armor {
+		data = append(data, '\n')
+	}
+	if err := writeOutput(e, *output, data); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+func runPolicyDecode(e *env, args []string) int {
+	fs := newPolicyFlags(e, "decode", "[access]")
+	output := fs.String("o", "", "output file, defaults to stdout")
+	if !fs.parse(args, 0, 1) {
+		return exitUsage
+	}
+	keyring, err := fs.loadKeyring()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	access, err := loadPolicy(e, fs.Arg(0), keyring)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	if keyring != nil {
+		keyring.NameAccess(access)
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, access); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	if err := writeOutput(e, *output, buf.Bytes()); err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+func runPolicyLint(e *env, args []string) int {
+	fs := newPolicyFlags(e, "lint", "policy")
+	jsonOutput := fs.Bool("json", false, "print the findings as JSON")
+	failOn := fs.String("fail-on", primus.PolicySeverities.High.String(), "lowest severity failing the check: low, medium or high")
+	if !fs.parse(args, 1, 1) {
+		return exitUsage
+	}
+	var threshold primus.PolicySeverity
+	if !threshold.FromString(*failOn) {
+		e.errorf("unknown severity %q", *failOn)
+		return exitUsage
+	}
+	keyring, err := fs.loadKeyring()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	access, err := loadPolicy(e, fs.Arg(0), keyring)
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	findings := primus.LintAccess(access, &primus.LintOptions{Keyring: keyring})
+	if *jsonOutput {
+		err = writeJSON(e.stdout, append([]primus.PolicyFinding{}, findings...))
+	} else {
+		for _, finding := range findings {
+			if _, err = fmt.Fprintln(e.stdout, finding); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	for _, finding := range findings {
+		if finding.Severity >= threshold {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+func runPolicyDiff(e *env, args []string) int {
+	fs := newPolicyFlags(e, "diff", "old new")
+	jsonOutput := fs.Bool("json", false, "print the changes as JSON")
+	failOnWeaker := fs.String("fail-on-weaker", "modify", "fail if the new policy weakens: modify, any or none")
+	if !fs.parse(args, 2, 2) {
+		return exitUsage
+	}
+	if *failOnWeaker != "modify" && *failOnWeaker != "any" && *failOnWeaker != "none" {
+		e.errorf("invalid -fail-on-weaker %q", *failOnWeaker)
+		return exitUsage
+	}
+	keyring, err := fs.loadKeyring()
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	var policies [2]*primus.Access
+	for i := range policies {
+		if policies[i], err = loadPolicy(e, fs.Arg(i), keyring); err != nil {
+			e.errorf("%s: %v", fs.Arg(i), err)
+			return exitUsage
+		}
+	}
+	diff := primus.DiffAccess(policies[0], policies[1])
+	if *jsonOutput {
+		err = writeJSON(e.stdout, diff)
+	} else {
+		for _, change := range diff.Changes {
+			if _, err = fmt.Fprintln(e.stdout, change); err != nil {
+				break
+			}
+		}
+		for _, blob := range diff.Weakened {
+			if err == nil {
+				_, err = fmt.Fprintf(e.stdout, "WEAKENED: %s\n", blob)
+			}
+		}
+	}
+	if err != nil {
+		e.errorf("%v", err)
+		return exitUsage
+	}
+	switch {
+	case *failOnWeaker == "any" && len(diff.Weakened) > 0,
+		*failOnWeaker == "modify" && diff.Weakens(primus.BlobNames.Modify):
+		return exitFailure
+	}
+	return exitOK
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"github.com/donutnomad/primus"
+	"github.com/samber/lo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPolicy writes a policy of three approvers, modifyQuorum of them must approve a MODIFY.
+func writeTestPolicy(t *testing.T, dir, name string, keys []string, modifyQuorum int) string {
+	blob := func(quorum int, delayMs int) string {
+		return fmt.Sprintf(`[{"name": "t", "delay_ms": %d, "time_limit_ms": 600000, "groups": [{"name": "ops", "quorum": %d, "public_keys": [%q, %q, %q]}]}]`,
+			delayMs, quorum, keys[0], keys[1], keys[2])
+	}
+	policy := fmt.Sprintf(`{"sign": %s, "block": %s, "unblock": %s, "modify": %s}`, blob(2, 0), blob(1, 0), blob(2, 0), blob(modifyQuorum, 3600000))
+	path := filepath.Join(dir, name)
+	lo.Must0(os.WriteFile(path, []byte(policy), 0644))
+	return path
+}
+
+func TestPolicy(t *testing.T) {
+	dir, keyring := t.TempDir(), t.TempDir()
+	var keys []string
+	for _, name := range []string{"alice", "bob", "carol"} {
+		lo.Must0(os.WriteFile(filepath.Join(keyring, name+".pem"), lo.Must(os.ReadFile(writeTestPublicKey(t, newTestKey()))), 0644))
+		keys = append(keys, name)
+	}
+	strict := writeTestPolicy(t, dir, "strict.json", keys, 3)
+	weak := writeTestPolicy(t, dir, "weak.json", keys, 1)
+
+	if code, _, _ := runCommand(t, "", "policy", "build", strict); code != exitUsage {
+		t.Fatal("expected unresolved key names without keyring", code)
+	}
+	code, stdout, stderr := runCommand(t, "", "policy", "build", "-keyring", keyring, strict)
+	if code != exitOK {
+		t.Fatal(code, stderr)
+	}
+	blob := strings.TrimSpace(stdout)
+	code, stdout, stderr = runCommand(t, "", "policy", "decode", "-keyring", keyring, blob)
+	if code != exitOK || !strings.Contains(stdout, `"key_names": [`) || !strings.Contains(stdout, `"carol"`) {
+		t.Fatal("unexpected decoded policy", code, stdout, stderr)
+	}
+
+	// decode output builds the same blob
+	decoded := filepath.Join(dir, "decoded.json")
+	lo.Must0(os.WriteFile(decoded, []byte(stdout), 0644))
+	if code, stdout, _ = runCommand(t, "", "policy", "build", decoded); code != exitOK || strings.TrimSpace(stdout) != blob {
+		t.Fatal("decoded policy doesn't round trip", stdout)
+	}
+
+	code, stdout, _ = runCommand(t, "", "policy", "modify-payload", "-format", primus.TokenFormats.Armor, "-keyring", keyring, strict)
+	if code != exitOK || !strings.HasPrefix(stdout, "-----BEGIN "+primus.ArmorTypes.Access) {
+		t.Fatal("unexpected modify payload", stdout)
+	}
+	access := new(primus.Access)
+	lo.Must0(access.Deserialize(lo.Must(primus.DecodeTokenText([]byte(stdout))).Bytes))
+	if len(access.Modify) != 1 || access.Modify[0].Groups[0].Quorum != 3 {
+		t.Fatal("invalid modify payload")
+	}
+
+	if code, stdout, _ = runCommand(t, "", "policy", "lint", "-keyring", keyring, strict); code != exitOK || stdout != "" {
+		t.Fatal("unexpected findings", code, stdout)
+	}
+	if code, stdout, _ = runCommand(t, "", "policy", "lint", "-keyring", keyring, weak); code != exitFailure || !strings.Contains(stdout, "a single approver can change the policy") {
+		t.Fatal("expected high findings", code, stdout)
+	}
+	if code, _, _ = runCommand(t, "", "policy", "lint", blob); code != exitOK {
+		t.Fatal("unexpected findings of the blob", code)
+	}
+
+	if code, stdout, _ = runCommand(t, "", "policy", "diff", "-keyring", keyring, strict, weak); code != exitFailure || !strings.Contains(stdout, "WEAKENED: ChangeAttributes") {
+		t.Fatal("expected weakened Modify blob", code, stdout)
+	}
+	if code, _, _ = runCommand(t, "", "policy", "diff", "-keyring", keyring, "-fail-on-weaker", "none", strict, weak); code != exitOK {
+		t.Fatal("unexpected exit code", code)
+	}
+	if code, stdout, _ = runCommand(t, "", "policy", "diff", "-keyring", keyring, weak, blob); code != exitOK || !strings.Contains(stdout, `ChangeAttributes/t: group "ops" quorum 1 -> 3`) {
+		t.Fatal("unexpected diff", code, stdout)
+	}
+}
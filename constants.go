@@ -1,5 +1,7 @@
 package primus
 
+import "strconv"
+
 type PayloadType int
 
 const EkaOperation PayloadType = 59
@@ -25,6 +27,37 @@ const APPROVAL_TOKEN PayloadType = 4181
 const CERTIFICATEDATA_BYTES PayloadType = 4105
 const TIME_SECONDS_SINCE_EPOCH PayloadType = 263
 
+var payloadTypeNames = map[PayloadType]string{
+	KEYCOUNT_INT32:           "KEYCOUNT_INT32",
+	EKA_OPERATION:            "EKA_OPERATION",
+	60:                       "APPROVAL_COUNT",
+	TIME_MINUTE:              "TIME_MINUTE",
+	TIME_SECOND:              "TIME_SECOND",
+	TOKEN_COUNT:              "TOKEN_COUNT",
+	GROUP_COUNT:              "GROUP_COUNT",
+	SIGNATURES_REQUIRED:      "SIGNATURES_REQUIRED",
+	TIME_SECONDS_SINCE_EPOCH: "TIME_SECONDS_SINCE_EPOCH",
+	LABEL_UTF8STRING:         "LABEL_UTF8STRING",
+	CERTIFICATEDATA_BYTES:    "CERTIFICATEDATA_BYTES",
+	SIGN_BLOB:                "SIGN_BLOB",
+	BLOCK_BLOB:               "BLOCK_BLOB",
+	UNBLOCK_BLOB:             "UNBLOCK_BLOB",
+	MODIFY_BLOB:              "MODIFY_BLOB",
+	PUBLIC_KEY_ENCODED:       "PUBLIC_KEY_ENCODED",
+	EKA_TIME_STAMP:           "EKA_TIME_STAMP",
+	APPROVAL_TOKEN:           "APPROVAL_TOKEN",
+	DER_SIGNATURE:            "DER_SIGNATURE",
+	EKA_SIGN_PAYLOAD:         "EKA_SIGN_PAYLOAD",
+	EKA_MODIFY_PAYLOAD:       "EKA_MODIFY_PAYLOAD",
+}
+
+func (t PayloadType) String() string {
+	if name, ok := payloadTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
 var namingSupport bool = true
 var supportsSeconds bool = true
 var serializeBlobAsOne = true
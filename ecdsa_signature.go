@@ -0,0 +1,133 @@
+package primus
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/cryptobyte"
+	asn1_ "golang.org/x/crypto/cryptobyte/asn1"
+	"math/big"
+)
+
+var ErrHighS = errors.New("signature S value is greater than half the curve order")
+
+// SignatureEncodingOptions controls the conversion between raw r||s and ASN1 ECDSA signatures.
+type SignatureEncodingOptions struct {
+	LowS   bool // replace S by N-S when S is greater than N/2
+	Strict bool // reject high S values and INTEGER encodings which aren't minimal
+}
+
+// RawToASN1Signature converts a fixed width r||s signature made by a key of alg into the ASN1
+// encoding expected in authorization tokens.
+func RawToASN1Signature(alg KeyAlgT, raw []byte, opts *SignatureEncodingOptions) ([]byte, error) {
+	curve := alg.Curve()
+	if curve == nil {
+		return nil, fmt.Errorf("%s keys don't create ECDSA signatures", alg)
+	}
+	size := curveOrderSize(curve)
+	if len(raw) != 2*size {
+		return nil, fmt.Errorf("%s signature must be %d bytes, got %d", alg, 2*size, len(raw))
+	}
+	r := new(big.Int).SetBytes(raw[:size])
+	s := new(big.Int).SetBytes(raw[size:])
+	if err := normalizeSignature(curve, r, s, opts); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(r)
+		b.AddASN1BigInt(s)
+	})
+	return b.Bytes()
+}
+
+// ASN1ToRawSignature converts an ASN1 ECDSA signature into r||s, both padded to the size of
+// the curve order of alg.
+func ASN1ToRawSignature(alg KeyAlgT, sig []byte, opts *SignatureEncodingOptions) ([]byte, error) {
+	curve := alg.Curve()
+	if curve == nil {
+		return nil, fmt.Errorf("%s keys don't create ECDSA signatures", alg)
+	}
+	r, s, err := parseASN1Signature(sig, opts != nil && opts.Strict)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeSignature(curve, r, s, opts); err != nil {
+		return nil, err
+	}
+	size := curveOrderSize(curve)
+	raw := make([]byte, 2*size)
+	r.FillBytes(raw[:size])
+	s.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// NormalizeASN1Signature re-encodes an ASN1 ECDSA signature minimally, applying opts.
+func NormalizeASN1Signature(alg KeyAlgT, sig []byte, opts *SignatureEncodingOptions) ([]byte, error) {
+	raw, err := ASN1ToRawSignature(alg, sig, opts)
+	if err != nil {
+		return nil, err
+	}
+	return RawToASN1Signature(alg, raw, nil)
+}
+
+// NewPrimusSignatureFromRaw wraps a raw r||s signature of a key of keyAlg.
+func NewPrimusSignatureFromRaw(signAlgorithm SignAlgT, keyAlg KeyAlgT, raw []byte, opts *SignatureEncodingOptions) (*PrimusSignature, error) {
+	sig, err := RawToASN1Signature(keyAlg, raw, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrimusSignature(signAlgorithm, sig), nil
+}
+
+func normalizeSignature(curve elliptic.Curve, r, s *big.Int, opts *SignatureEncodingOptions) error {
+	n := curve.Params().N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return errors.New("signature values out of range")
+	}
+	if opts == nil {
+		return nil
+	}
+	halfOrder := new(big.Int).Rsh(n, 1)
+	if s.Cmp(halfOrder) > 0 {
+		if opts.Strict {
+			return ErrHighS
+		}
+		if opts.LowS {
+			s.Sub(n, s)
+		}
+	}
+	return nil
+}
+
+// parseASN1Signature reads SEQUENCE { r INTEGER, s INTEGER }. Unless strict, INTEGERs with
+// superfluous leading zeros or a missing sign byte are accepted, as written by some tokens.
+func parseASN1Signature(sig []byte, strict bool) (r, s *big.Int, err error) {
+	var input = cryptobyte.String(sig)
+	var inner cryptobyte.String
+	if !input.ReadASN1(&inner, asn1_.SEQUENCE) || !input.Empty() {
+		return nil, nil, errors.New("invalid ASN1 signature")
+	}
+	r, s = new(big.Int), new(big.Int)
+	for _, v := range []*big.Int{r, s} {
+		var ok bool
+		if strict {
+			ok = inner.ReadASN1Integer(v)
+		} else {
+			var content cryptobyte.String
+			ok = inner.ReadASN1(&content, asn1_.INTEGER) && len(content) > 0
+			v.SetBytes(content)
+		}
+		if !ok {
+			return nil, nil, errors.New("invalid ASN1 signature integer")
+		}
+	}
+	if !inner.Empty() {
+		return nil, nil, errors.New("trailing data in ASN1 signature")
+	}
+	return r, s, nil
+}
+
+func curveOrderSize(curve elliptic.Curve) int {
+	return (curve.Params().N.BitLen() + 7) / 8
+}
@@ -0,0 +1,76 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"github.com/samber/lo"
+	"math/big"
+	"testing"
+)
+
+func TestRawASN1SignatureConversion(t *testing.T) {
+	digest := sha256.Sum256([]byte("message"))
+	for alg, size := range map[KeyAlgT]int{
+		KeyAlg.SECP224R1: 28, KeyAlg.SECP256R1: 32, KeyAlg.SECP384R1: 48, KeyAlg.SECP521R1: 66, KeyAlg.SECP256K1: 32,
+	} {
+		priv := lo.Must(ecdsa.GenerateKey(alg.Curve(), rand.Reader))
+		var sig []byte
+		if alg == KeyAlg.SECP256K1 {
+			sig = signSecp256k1(toSecp256k1PrivateKey(priv), digest[:])
+		} else {
+			sig = lo.Must(ecdsa.SignASN1(rand.Reader, priv, digest[:]))
+		}
+		raw := lo.Must(ASN1ToRawSignature(alg, sig, nil))
+		if len(raw) != 2*size {
+			t.Fatal("invalid raw size", alg, len(raw))
+		}
+		if !bytes.Equal(lo.Must(RawToASN1Signature(alg, raw, nil)), sig) {
+			t.Fatal("invalid round trip", alg)
+		}
+		if _, err := RawToASN1Signature(alg, raw[1:], nil); err == nil {
+			t.Fatal("expected size error", alg)
+		}
+	}
+	if _, err := RawToASN1Signature(KeyAlg.ED25519, make([]byte, 64), nil); err == nil {
+		t.Fatal("Ed25519 has no ECDSA signatures")
+	}
+}
+
+func TestSignatureLowS(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	digest := sha256.Sum256([]byte("message"))
+	n := elliptic.P256().Params().N
+	raw := lo.Must(ASN1ToRawSignature(KeyAlg.SECP256R1, lo.Must(ecdsa.SignASN1(rand.Reader, priv, digest[:])), nil))
+	s := new(big.Int).SetBytes(raw[32:])
+	if s.Cmp(new(big.Int).Rsh(n, 1)) <= 0 {
+		s.Sub(n, s)
+	}
+	high := append(append([]byte{}, raw[:32]...), s.FillBytes(make([]byte, 32))...)
+
+	if _, err := RawToASN1Signature(KeyAlg.SECP256R1, high, &SignatureEncodingOptions{Strict: true}); !errors.Is(err, ErrHighS) {
+		t.Fatal("expected ErrHighS, got", err)
+	}
+	low := lo.Must(RawToASN1Signature(KeyAlg.SECP256R1, high, &SignatureEncodingOptions{LowS: true}))
+	if !ecdsa.VerifyASN1(&priv.PublicKey, digest[:], low) {
+		t.Fatal("normalized signature must verify")
+	}
+	if _, err := NormalizeASN1Signature(KeyAlg.SECP256R1, low, &SignatureEncodingOptions{Strict: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignatureStrictEncoding(t *testing.T) {
+	// r = 1 with a superfluous leading zero, s = 0x80 without its sign byte
+	sig := []byte{0x30, 0x07, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x80}
+	if _, err := ASN1ToRawSignature(KeyAlg.SECP256R1, sig, &SignatureEncodingOptions{Strict: true}); err == nil {
+		t.Fatal("strict mode must reject non minimal integers")
+	}
+	normalized := lo.Must(NormalizeASN1Signature(KeyAlg.SECP256R1, sig, nil))
+	if !bytes.Equal(normalized, []byte{0x30, 0x07, 0x02, 0x01, 0x01, 0x02, 0x02, 0x00, 0x80}) {
+		t.Fatalf("invalid normalized signature %x", normalized)
+	}
+}
@@ -0,0 +1,50 @@
+package primus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// HSM is the Extended Key Authorization interface of a Primus HSM. The client of the simulator
+// package implements it, code written against HSM can be tested without a device.
+type HSM interface {
+	// IntegrityKey returns the public key which signs the timestamps of approval tokens.
+	IntegrityKey(ctx context.Context) (*ParsedPublicKey, error)
+	// CreateKey creates a key as described by template.
+	CreateKey(ctx context.Context, template *KeyTemplate) (*ParsedPublicKey, error)
+	// Key returns the current state of the key called label, ACCESS_BLOCKED is set once blocked.
+	Key(ctx context.Context, label string) (*KeyTemplate, error)
+	PublicKey(ctx context.Context, label string) (*ParsedPublicKey, error)
+	// RequestApproval returns the encoded approval token of operation on the key called label, to
+	// be signed by the approvers with AuthorizeBytes.
+	RequestApproval(ctx context.Context, operation ApprovalTokenOpType, label string, payload []byte) ([]byte, error)
+	// Execute runs the operation approved by tokens, SIGN returns the signature.
+	Execute(ctx context.Context, tokens []*AuthorizationToken) ([]byte, error)
+}
+
+// EncodeAuthorizationTokens concatenates tokens as length-headed blobs.
+func EncodeAuthorizationTokens(tokens []*AuthorizationToken) []byte {
+	var out []byte
+	for _, token := range tokens {
+		out = append(out, lengthHeader(optionallyCutLengthHeader(token.GetEncoding()))...)
+	}
+	return out
+}
+
+// DecodeAuthorizationTokens splits the output of EncodeAuthorizationTokens.
+func DecodeAuthorizationTokens(data []byte) ([]*AuthorizationToken, error) {
+	var tokens []*AuthorizationToken
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errors.New("length header too short")
+		}
+		length := int(binary.LittleEndian.Uint32(data[:4]))
+		if length > len(data)-4 {
+			return nil, errors.New("data shorter than length header")
+		}
+		tokens = append(tokens, NewPrimusAuthorizationToken(copySlice(data[:4+length]), ""))
+		data = data[4+length:]
+	}
+	return tokens, nil
+}
@@ -0,0 +1,24 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestEncodeAuthorizationTokens(t *testing.T) {
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.BLOCK, nil, "wallet")
+	tokens := lo.Times(2, func(int) *AuthorizationToken {
+		return lo.Must(approval.Authorize(lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)), EcdsaSignAlg.SHA256withECDSA))
+	})
+	encoded := EncodeAuthorizationTokens(tokens)
+	decoded := lo.Must(DecodeAuthorizationTokens(encoded))
+	if len(decoded) != 2 || string(decoded[1].GetEncoding()) != string(tokens[1].GetEncoding()) {
+		t.Fatal("tokens don't round trip")
+	}
+	if _, err := DecodeAuthorizationTokens(encoded[:10]); err == nil {
+		t.Fatal("expected truncated tokens")
+	}
+}
@@ -1,6 +1,18 @@
 package primus
 
-import "strings"
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/donutnomad/blockchain-alg/xx509"
+	"math/big"
+	"strings"
+)
 
 type KeyAlgT string
 
@@ -8,23 +20,23 @@ func (t KeyAlgT) String() string {
 	return string(t)
 }
 
+// FromString accepts the names of KeyAlg as well as common aliases like P-256, secp256r1,
+// prime256v1, Ed25519 or X25519, case insensitive.
 func (t *KeyAlgT) FromString(s string) bool {
-	switch KeyAlgT(s) {
-	case KeyAlg.SECP224R1:
+	switch strings.ToUpper(s) {
+	case "SECP224R1", "P-224", "P224":
 		*t = KeyAlg.SECP224R1
-	case KeyAlg.SECP256K1:
+	case "SECP256K1":
 		*t = KeyAlg.SECP256K1
-	case KeyAlg.SECP256R1:
+	case "SECP256R1", "P-256", "P256", "PRIME256V1":
 		*t = KeyAlg.SECP256R1
-	case KeyAlg.SECP384R1:
+	case "SECP384R1", "P-384", "P384":
 		*t = KeyAlg.SECP384R1
-	case KeyAlg.SECP521R1:
+	case "SECP521R1", "P-521", "P521":
 		*t = KeyAlg.SECP521R1
-	case KeyAlg.ED25519:
+	case "ED25519":
 		*t = KeyAlg.ED25519
-	case KeyAlg.X25519:
-		*t = KeyAlg.X25519
-	case "X25519":
+	case "X25519", "CURVE25519":
 		*t = KeyAlg.X25519
 	default:
 		*t = ""
@@ -37,6 +49,131 @@ func (t *KeyAlgT) IsRsSignature() bool {
 	return strings.HasPrefix(t.String(), "SECP") || *t == KeyAlg.ED25519 || *t == KeyAlg.X25519
 }
 
+// Curve returns the elliptic curve of ECDSA key algorithms and nil for the others.
+func (t KeyAlgT) Curve() elliptic.Curve {
+	switch t {
+	case KeyAlg.SECP224R1:
+		return elliptic.P224()
+	case KeyAlg.SECP256R1:
+		return elliptic.P256()
+	case KeyAlg.SECP384R1:
+		return elliptic.P384()
+	case KeyAlg.SECP521R1:
+		return elliptic.P521()
+	case KeyAlg.SECP256K1:
+		return secp256k1.S256()
+	}
+	return nil
+}
+
+// OID returns the named curve OID of ECDSA keys and the algorithm OID of RFC 8410 keys.
+func (t KeyAlgT) OID() asn1.ObjectIdentifier {
+	switch t {
+	case KeyAlg.SECP224R1:
+		return asn1.ObjectIdentifier{1, 3, 132, 0, 33}
+	case KeyAlg.SECP256K1:
+		return asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	case KeyAlg.SECP256R1:
+		return asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	case KeyAlg.SECP384R1:
+		return asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	case KeyAlg.SECP521R1:
+		return asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+	case KeyAlg.ED25519:
+		return xx509.OidPublicKeyEd25519
+	case KeyAlg.X25519:
+		return xx509.OidPublicKeyX25519
+	}
+	return nil
+}
+
+// KeySize returns the size of the key in bits.
+func (t KeyAlgT) KeySize() int {
+	if curve := t.Curve(); curve != nil {
+		return curve.Params().BitSize
+	}
+	switch t {
+	case KeyAlg.ED25519, KeyAlg.X25519:
+		return 256
+	}
+	return 0
+}
+
+// SignatureSize returns the size of raw signatures in bytes, r||s for ECDSA. It's 0 for keys
+// which can't sign.
+func (t KeyAlgT) SignatureSize() int {
+	if curve := t.Curve(); curve != nil {
+		return 2 * curveOrderSize(curve)
+	}
+	if t == KeyAlg.ED25519 {
+		return ed25519.SignatureSize
+	}
+	return 0
+}
+
+// SignatureAlgorithms returns the registered algorithms which accept keys of t for signing.
+func (t KeyAlgT) SignatureAlgorithms() []SignatureAlgorithm {
+	var pub crypto.PublicKey
+	if curve := t.Curve(); curve != nil {
+		pub = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).Set(curve.Params().Gx), Y: new(big.Int).Set(curve.Params().Gy)}
+	} else if t == KeyAlg.ED25519 {
+		pub = make(ed25519.PublicKey, ed25519.PublicKeySize)
+	} else {
+		return nil
+	}
+	var ret []SignatureAlgorithm
+	for _, v := range SignatureAlgorithms() {
+		if v.CheckKey(pub) == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// IsCompatible reports whether keys of t can sign with alg.
+func (t KeyAlgT) IsCompatible(alg SignAlgT) bool {
+	for _, v := range t.SignatureAlgorithms() {
+		if v.Name() == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectKeyAlg returns the key algorithm of a PKIX encoded public key, EC points may be compressed.
+func DetectKeyAlg(pkix []byte) (KeyAlgT, error) {
+	pub, err := parsePublicKeyDER(pkix)
+	if err != nil {
+		return "", err
+	}
+	return KeyAlgOf(pub)
+}
+
+// KeyAlgOf returns the key algorithm of pub.
+func KeyAlgOf(pub crypto.PublicKey) (KeyAlgT, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if isSecp256k1(key.Curve) {
+			return KeyAlg.SECP256K1, nil
+		}
+		for _, v := range keyAlgs {
+			if curve := v.Curve(); curve != nil && key.Curve == curve {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return KeyAlg.ED25519, nil
+	case *ecdh.PublicKey:
+		if key.Curve() == ecdh.X25519() {
+			return KeyAlg.X25519, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported public key type %T", pub)
+}
+
+var keyAlgs = []KeyAlgT{KeyAlg.SECP224R1, KeyAlg.SECP256K1, KeyAlg.SECP256R1, KeyAlg.SECP384R1, KeyAlg.SECP521R1, KeyAlg.ED25519, KeyAlg.X25519}
+
 var KeyAlg = struct {
 	SECP224R1 KeyAlgT // P-224
 	SECP256K1 KeyAlgT
@@ -0,0 +1,72 @@
+package primus
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestKeyAlgFromString(t *testing.T) {
+	for _, alg := range []KeyAlgT{KeyAlg.SECP224R1, KeyAlg.SECP256K1, KeyAlg.SECP256R1, KeyAlg.SECP384R1, KeyAlg.SECP521R1, KeyAlg.ED25519, KeyAlg.X25519} {
+		var parsed KeyAlgT
+		if !parsed.FromString(alg.String()) || parsed != alg {
+			t.Fatal("invalid round trip", alg)
+		}
+	}
+	for alias, expected := range map[string]KeyAlgT{"P-256": KeyAlg.SECP256R1, "prime256v1": KeyAlg.SECP256R1, "CURVE25519": KeyAlg.X25519, "x25519": KeyAlg.X25519, "Ed25519": KeyAlg.ED25519} {
+		var parsed KeyAlgT
+		if !parsed.FromString(alias) || parsed != expected {
+			t.Fatal("invalid alias", alias, parsed)
+		}
+		var again KeyAlgT
+		if !again.FromString(parsed.String()) || again != parsed {
+			t.Fatal("unstable round trip", alias, parsed)
+		}
+	}
+}
+
+func TestDetectKeyAlg(t *testing.T) {
+	for _, alg := range []KeyAlgT{KeyAlg.SECP224R1, KeyAlg.SECP256K1, KeyAlg.SECP256R1, KeyAlg.SECP384R1, KeyAlg.SECP521R1} {
+		priv := lo.Must(ecdsa.GenerateKey(alg.Curve(), rand.Reader))
+		if detected := lo.Must(DetectKeyAlg(lo.Must(marshalPKIXPublicKey(&priv.PublicKey)))); detected != alg {
+			t.Fatal("invalid key algorithm", alg, detected)
+		}
+		if detected := lo.Must(DetectKeyAlg(compressedPKIX(alg, &priv.PublicKey))); detected != alg {
+			t.Fatal("invalid key algorithm of compressed key", alg, detected)
+		}
+		if len(lo.Must(ASN1ToRawSignature(alg, lo.Must(FindSignatureAlgorithm(EcdsaSignAlg.SHA512withECDSA).SignMessage(priv, nil)), nil))) != alg.SignatureSize() {
+			t.Fatal("invalid signature size", alg)
+		}
+	}
+	edPub, _ := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	if lo.Must(DetectKeyAlg(lo.Must(x509.MarshalPKIXPublicKey(edPub)))) != KeyAlg.ED25519 {
+		t.Fatal("expected Ed25519")
+	}
+	xPriv := lo.Must(ecdh.X25519().GenerateKey(rand.Reader))
+	if lo.Must(DetectKeyAlg(lo.Must(x509.MarshalPKIXPublicKey(xPriv.PublicKey())))) != KeyAlg.X25519 || KeyAlg.X25519.String() != "CURVE25519" {
+		t.Fatal("expected X25519")
+	}
+}
+
+func TestKeyAlgSignatureAlgorithms(t *testing.T) {
+	if !KeyAlg.SECP256R1.IsCompatible(EcdsaSignAlg.SHA256withECDSA) || KeyAlg.SECP521R1.IsCompatible(EcdsaSignAlg.SHA256withECDSA) {
+		t.Fatal("invalid ECDSA compatibility")
+	}
+	if !KeyAlg.ED25519.IsCompatible(EddsaSignAlg.Ed25519) || KeyAlg.ED25519.IsCompatible(EcdsaSignAlg.SHA256withECDSA) {
+		t.Fatal("invalid Ed25519 compatibility")
+	}
+	if len(KeyAlg.X25519.SignatureAlgorithms()) != 0 {
+		t.Fatal("X25519 keys can't sign")
+	}
+
+	priv := lo.Must(ecdsa.GenerateKey(KeyAlg.SECP384R1.Curve(), rand.Reader))
+	group := &AccessGroup{Name: "approvers", Quorum: 1, PublicKeys: []Publickey{NewPublicKeyImpl("", lo.Must(x509.MarshalPKIXPublicKey(&priv.PublicKey)))}}
+	lo.Must0(group.CheckSignatureAlgorithm(EcdsaSignAlg.SHA384withECDSA))
+	if group.CheckSignatureAlgorithm(EcdsaSignAlg.SHA256withECDSA) == nil || group.CheckSignatureAlgorithm(EddsaSignAlg.Ed25519) == nil {
+		t.Fatal("expected incompatible algorithm")
+	}
+}
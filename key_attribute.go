@@ -1,5 +1,12 @@
 package primus
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type KeyAttributeT struct {
 	Name string
 	typ  int
@@ -22,10 +29,14 @@ func (t KeyAttributeT) Flag() int {
 	return t.flag
 }
 func (t KeyAttributeT) IsAccess() bool {
-	return t.typ == 1
+	return t.typ == 2
 }
 func (t KeyAttributeT) IsCapability() bool {
-	return t.typ == 2
+	return t.typ == 1
+}
+
+func (t KeyAttributeT) String() string {
+	return t.Name
 }
 
 var capabilityFlags = []KeyAttributeT{
@@ -35,6 +46,28 @@ var capabilityFlags = []KeyAttributeT{
 	KeyAttribute.CapabilityIntegrity,
 }
 
+var accessFlags = []KeyAttributeT{
+	KeyAttribute.AccessAlwaysSensitive,
+	KeyAttribute.AccessNoPublicKey,
+	KeyAttribute.AccessBlocked,
+	KeyAttribute.AccessIndestructible,
+	KeyAttribute.AccessToken,
+	KeyAttribute.AccessCopyable,
+	KeyAttribute.AccessExtractable,
+	KeyAttribute.AccessSensitive,
+	KeyAttribute.AccessModifiable,
+}
+
+// FindKeyAttribute returns the attribute called name, e.g. CAPABILITY_SIGN.
+func FindKeyAttribute(name string) (KeyAttributeT, bool) {
+	for _, v := range append(capabilityFlags, accessFlags...) {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return KeyAttributeT{}, false
+}
+
 var KeyAttribute = struct {
 	// Public key can verify signatures, default is true
 	CapabilitySign KeyAttributeT
@@ -83,3 +116,137 @@ var KeyAttribute = struct {
 	AccessSensitive:       KeyAttributeT{"ACCESS_SENSITIVE", 2, 1},
 	AccessModifiable:      KeyAttributeT{"ACCESS_MODIFIABLE", 2, 4},
 }
+
+var ErrKeyAttributeTransition = errors.New("illegal key attribute transition")
+
+// KeyAttributes holds the value of key attributes. Attributes which are absent take the value the
+// HSM applies when creating a key, see Get.
+type KeyAttributes map[KeyAttributeT]bool
+
+// ParseKeyAttributes decodes the capability and access flag words of a key, every known
+// attribute is set.
+func ParseKeyAttributes(capabilities, access int) (KeyAttributes, error) {
+	ret := make(KeyAttributes)
+	for _, word := range []struct {
+		flags int
+		attrs []KeyAttributeT
+		name  string
+	}{
+		{capabilities, capabilityFlags, "capability"},
+		{access, accessFlags, "access"},
+	} {
+		known := 0
+		for _, attr := range word.attrs {
+			ret[attr] = attr.HasFlag(word.flags)
+			known |= attr.flag
+		}
+		if unknown := word.flags &^ known; unknown != 0 {
+			return nil, fmt.Errorf("unknown %s flags 0x%x", word.name, unknown)
+		}
+	}
+	return ret, nil
+}
+
+// ParseKeyAttributeNames parses attribute names separated by '|', ',' or spaces, as written
+// by String. The named attributes are true, all others false.
+func ParseKeyAttributeNames(s string) (KeyAttributes, error) {
+	ret := make(KeyAttributes)
+	for _, attr := range append(capabilityFlags, accessFlags...) {
+		ret[attr] = false
+	}
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == '|' || r == ',' || r == ' '
+	}) {
+		attr, ok := FindKeyAttribute(strings.ToUpper(name))
+		if !ok {
+			return nil, fmt.Errorf("unknown key attribute %q", name)
+		}
+		ret[attr] = true
+	}
+	return ret, nil
+}
+
+// Get returns the value of attr, falling back to the default of the HSM.
+func (a KeyAttributes) Get(attr KeyAttributeT) bool {
+	if v, ok := a[attr]; ok {
+		return v
+	}
+	// the HSM also defaults these to true, leaving them out of the encoded flags would clear them
+	switch attr {
+	case KeyAttribute.CapabilitySign, KeyAttribute.CapabilityDecrypt, KeyAttribute.CapabilityDerive, KeyAttribute.AccessToken:
+		return true
+	}
+	return DefaultKeyAttribute()[attr]
+}
+
+// WithDefaults returns a copy of a where absent attributes are set to their default.
+func (a KeyAttributes) WithDefaults() KeyAttributes {
+	ret := make(KeyAttributes)
+	for _, attr := range append(capabilityFlags, accessFlags...) {
+		ret[attr] = a.Get(attr)
+	}
+	return ret
+}
+
+// Flags encodes the attributes which are true, defaults applied, into the capability and
+// access flag words.
+func (a KeyAttributes) Flags() (capabilities, access int) {
+	for attr, v := range a.WithDefaults() {
+		if !v {
+			continue
+		}
+		if attr.IsCapability() {
+			capabilities |= attr.flag
+		} else {
+			access |= attr.flag
+		}
+	}
+	return capabilities, access
+}
+
+// String lists the names of the true attributes, defaults applied, capabilities first.
+func (a KeyAttributes) String() string {
+	var names []KeyAttributeT
+	for attr, v := range a.WithDefaults() {
+		if v {
+			names = append(names, attr)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].typ != names[j].typ {
+			return names[i].typ < names[j].typ
+		}
+		return names[i].flag > names[j].flag
+	})
+	var sb strings.Builder
+	for i, attr := range names {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(attr.Name)
+	}
+	return sb.String()
+}
+
+// CheckTransition reports whether the attributes of a key may be modified from a to next.
+// Nothing may change once ACCESS_MODIFIABLE is false, ACCESS_ALWAYS_SENSITIVE and
+// ACCESS_SENSITIVE can't be cleared and ACCESS_EXTRACTABLE can't be set again.
+func (a KeyAttributes) CheckTransition(next KeyAttributes) error {
+	from, to := a.WithDefaults(), next.WithDefaults()
+	for _, attr := range append(capabilityFlags, accessFlags...) {
+		if from[attr] == to[attr] {
+			continue
+		}
+		if !from[KeyAttribute.AccessModifiable] {
+			return fmt.Errorf("%w: key is not modifiable, %s can't change", ErrKeyAttributeTransition, attr)
+		}
+		switch {
+		case attr == KeyAttribute.AccessModifiable && to[attr],
+			attr == KeyAttribute.AccessAlwaysSensitive && !to[attr],
+			attr == KeyAttribute.AccessSensitive && !to[attr],
+			attr == KeyAttribute.AccessExtractable && to[attr]:
+			return fmt.Errorf("%w: %s can't change from %t to %t", ErrKeyAttributeTransition, attr, from[attr], to[attr])
+		}
+	}
+	return nil
+}
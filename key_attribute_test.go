@@ -0,0 +1,83 @@
+package primus
+
+import (
+	"errors"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestKeyAttributeClassification(t *testing.T) {
+	for _, v := range capabilityFlags {
+		if !v.IsCapability() || v.IsAccess() {
+			t.Fatal("invalid classification", v)
+		}
+	}
+	for _, v := range accessFlags {
+		if !v.IsAccess() || v.IsCapability() {
+			t.Fatal("invalid classification", v)
+		}
+	}
+}
+
+func TestKeyAttributes(t *testing.T) {
+	attrs := lo.Must(ParseKeyAttributes(4|128, 1|4|32|4096))
+	if !attrs.Get(KeyAttribute.CapabilityIntegrity) || attrs.Get(KeyAttribute.CapabilityDecrypt) || attrs.Get(KeyAttribute.AccessExtractable) {
+		t.Fatal("invalid attributes", attrs)
+	}
+	if capabilities, access := attrs.Flags(); capabilities != 4|128 || access != 1|4|32|4096 {
+		t.Fatal("invalid flags", capabilities, access)
+	}
+	expected := "CAPABILITY_INTEGRITY|CAPABILITY_SIGN|ACCESS_ALWAYS_SENSITIVE|ACCESS_TOKEN|ACCESS_MODIFIABLE|ACCESS_SENSITIVE"
+	if attrs.String() != expected {
+		t.Fatal("invalid string", attrs.String())
+	}
+	parsed := lo.Must(ParseKeyAttributeNames(expected))
+	if parsed.String() != expected {
+		t.Fatal("invalid parsed attributes", parsed.String())
+	}
+	if _, err := ParseKeyAttributes(0, 1<<20); err == nil {
+		t.Fatal("expected unknown flag error")
+	}
+	if _, err := ParseKeyAttributeNames("ACCESS_UNKNOWN"); err == nil {
+		t.Fatal("expected unknown name error")
+	}
+
+	// attributes documented as "default is true"
+	for _, attr := range []KeyAttributeT{KeyAttribute.CapabilitySign, KeyAttribute.CapabilityDecrypt, KeyAttribute.CapabilityDerive,
+		KeyAttribute.AccessToken, KeyAttribute.AccessSensitive, KeyAttribute.AccessModifiable} {
+		if !(KeyAttributes{}).Get(attr) {
+			t.Fatal("missing default", attr)
+		}
+	}
+	if len(DefaultKeyAttribute()) != 3 {
+		t.Fatal("DefaultKeyAttribute changed", DefaultKeyAttribute())
+	}
+	defaults := KeyAttributes{}
+	if defaults.String() != "CAPABILITY_DERIVE|CAPABILITY_SIGN|CAPABILITY_DECRYPT|ACCESS_TOKEN|ACCESS_MODIFIABLE|ACCESS_SENSITIVE" {
+		t.Fatal("invalid defaults", defaults.String())
+	}
+}
+
+func TestKeyAttributesTransition(t *testing.T) {
+	current := KeyAttributes{KeyAttribute.AccessAlwaysSensitive: true}
+	if err := current.CheckTransition(KeyAttributes{KeyAttribute.AccessAlwaysSensitive: true, KeyAttribute.AccessBlocked: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, next := range []KeyAttributes{
+		{},
+		{KeyAttribute.AccessAlwaysSensitive: true, KeyAttribute.AccessExtractable: true},
+		{KeyAttribute.AccessAlwaysSensitive: true, KeyAttribute.AccessSensitive: false},
+	} {
+		if err := current.CheckTransition(next); !errors.Is(err, ErrKeyAttributeTransition) {
+			t.Fatal("expected transition error, got", err, next)
+		}
+	}
+
+	fixed := KeyAttributes{KeyAttribute.AccessModifiable: false}
+	if err := fixed.CheckTransition(KeyAttributes{}); !errors.Is(err, ErrKeyAttributeTransition) {
+		t.Fatal("ACCESS_MODIFIABLE can't be set again, got", err)
+	}
+	if err := fixed.CheckTransition(KeyAttributes{KeyAttribute.AccessModifiable: false, KeyAttribute.AccessBlocked: true}); !errors.Is(err, ErrKeyAttributeTransition) {
+		t.Fatal("key must not be modifiable, got", err)
+	}
+}
@@ -0,0 +1,50 @@
+package primus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyTemplate describes a key to be created on the HSM.
+type KeyTemplate struct {
+	Label      string
+	Algorithm  KeyAlgT
+	Attributes KeyAttributes
+	// Access is the initial policy of the key, nil creates the key without one.
+	Access *Access
+	// Cryptocurrency is the currency type of the key, required with ACCESS_NO_PUBLIC_KEY.
+	Cryptocurrency string
+}
+
+// Validate checks the template against the rules of the HSM for new keys.
+func (t *KeyTemplate) Validate() error {
+	if len(t.Label) == 0 {
+		return errors.New("key template: missing label")
+	}
+	var alg KeyAlgT
+	if !alg.FromString(t.Algorithm.String()) || alg != t.Algorithm {
+		return fmt.Errorf("key template: unknown key algorithm %q", t.Algorithm)
+	}
+	attrs := t.Attributes.WithDefaults()
+	if capabilities, _ := attrs.Flags(); capabilities == 0 {
+		return errors.New("key template: key has no capability")
+	}
+	if attrs[KeyAttribute.AccessNoPublicKey] && len(t.Cryptocurrency) == 0 {
+		return fmt.Errorf("key template: %s requires a cryptocurrency type", KeyAttribute.AccessNoPublicKey)
+	}
+	if attrs[KeyAttribute.AccessAlwaysSensitive] && !attrs[KeyAttribute.AccessSensitive] {
+		return fmt.Errorf("key template: %s requires %s", KeyAttribute.AccessAlwaysSensitive, KeyAttribute.AccessSensitive)
+	}
+	if t.Access != nil {
+		for _, name := range []BlobName{BlobNames.Signing, BlobNames.Block, BlobNames.UnBlock, BlobNames.Modify} {
+			for _, token := range t.Access.GetBlob(name) {
+				for _, group := range token.Groups {
+					if err := group.Validate(); err != nil {
+						return fmt.Errorf("key template: %s: %w", name, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
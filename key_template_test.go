@@ -0,0 +1,61 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestKeyTemplate(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approver := NewPublicKeyImpl("alice", lo.Must(x509.MarshalPKIXPublicKey(&priv.PublicKey)))
+	tokens := []*AccessToken{{
+		Name:        "default",
+		TimeLimitMs: 60 * 60 * 1000,
+		Groups:      []*AccessGroup{{Name: "approvers", Quorum: 1, PublicKeys: []Publickey{approver}}},
+	}}
+	template := &KeyTemplate{
+		Label:     "btc_01",
+		Algorithm: KeyAlg.SECP256K1,
+		Attributes: KeyAttributes{
+			KeyAttribute.CapabilityDecrypt: false,
+			KeyAttribute.AccessNoPublicKey: true,
+		},
+		Access:         NewAccess(tokens, tokens, tokens, tokens),
+		Cryptocurrency: "BTC",
+	}
+	lo.Must0(template.Validate())
+
+	for _, invalid := range []func(*KeyTemplate){
+		func(t *KeyTemplate) { t.Label = "" },
+		func(t *KeyTemplate) { t.Algorithm = "RSA" },
+		func(t *KeyTemplate) { t.Cryptocurrency = "" },
+		func(t *KeyTemplate) {
+			t.Attributes[KeyAttribute.AccessSensitive] = false
+			t.Attributes[KeyAttribute.AccessAlwaysSensitive] = true
+		},
+		func(t *KeyTemplate) {
+			t.Access = NewAccess([]*AccessToken{{Groups: []*AccessGroup{{Quorum: 2, PublicKeys: []Publickey{approver}}}}}, nil, nil, nil)
+		},
+	} {
+		copied := *template
+		copied.Attributes = KeyAttributes{}
+		for k, v := range template.Attributes {
+			copied.Attributes[k] = v
+		}
+		invalid(&copied)
+		if err := copied.Validate(); err == nil {
+			t.Fatal("expected validation error", copied)
+		}
+	}
+
+	integrity := &KeyTemplate{
+		Label:      "timestamp",
+		Algorithm:  KeyAlg.SECP256R1,
+		Attributes: KeyAttributes{KeyAttribute.CapabilityIntegrity: true},
+	}
+	lo.Must0(integrity.Validate())
+}
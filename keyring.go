@@ -0,0 +1,286 @@
+package primus
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrUnknownSigner = errors.New("signer is not in the keyring")
+var ErrKeyRevoked = errors.New("signer key is revoked")
+
+// Identity ties an approver name to a public key.
+type Identity struct {
+	Name     string
+	Owner    string
+	Device   string
+	Enrolled time.Time
+	// Revoked is the time the key was revoked, zero while the key is active.
+	Revoked time.Time
+	Key     *ParsedPublicKey
+}
+
+// IsRevoked reports whether the key is revoked at t.
+func (i *Identity) IsRevoked(t time.Time) bool {
+	return !i.Revoked.IsZero() && !t.Before(i.Revoked)
+}
+
+type identityJson struct {
+	Name      string          `json:"name"`
+	Owner     string          `json:"owner,omitempty"`
+	Device    string          `json:"device,omitempty"`
+	Enrolled  *time.Time      `json:"enrolled,omitempty"`
+	Revoked   *time.Time      `json:"revoked,omitempty"`
+	PublicKey json.RawMessage `json:"public_key"`
+}
+
+// MarshalJSON writes the key as PEM.
+func (i *Identity) MarshalJSON() ([]byte, error) {
+	pemKey, err := i.Key.Export(PublicKeyFormats.PEM)
+	if err != nil {
+		return nil, err
+	}
+	obj := identityJson{Name: i.Name, Owner: i.Owner, Device: i.Device}
+	if !i.Enrolled.IsZero() {
+		obj.Enrolled = &i.Enrolled
+	}
+	if !i.Revoked.IsZero() {
+		obj.Revoked = &i.Revoked
+	}
+	if obj.PublicKey, err = json.Marshal(string(pemKey)); err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON accepts the key as JWK object, or as string holding hex DER or any format of
+// ImportPublicKey.
+func (i *Identity) UnmarshalJSON(bs []byte) error {
+	var obj identityJson
+	if err := json.Unmarshal(bs, &obj); err != nil {
+		return err
+	}
+	if len(obj.Name) == 0 {
+		return errors.New("identity has no name")
+	}
+	var keyData []byte
+	var text string
+	if err := json.Unmarshal(obj.PublicKey, &text); err == nil {
+		if keyData, err = hex.DecodeString(text); err != nil {
+			keyData = []byte(text)
+		}
+	} else {
+		keyData = obj.PublicKey
+	}
+	key, err := ImportPublicKey(obj.Name, keyData)
+	if err != nil {
+		return fmt.Errorf("identity %q: %w", obj.Name, err)
+	}
+	*i = Identity{Name: obj.Name, Owner: obj.Owner, Device: obj.Device, Key: key}
+	if obj.Enrolled != nil {
+		i.Enrolled = *obj.Enrolled
+	}
+	if obj.Revoked != nil {
+		i.Revoked = *obj.Revoked
+	}
+	return nil
+}
+
+// Keyring is a trust store of approver identities, names and keys are unique.
+type Keyring struct {
+	identities []*Identity
+}
+
+func NewKeyring(identities ...*Identity) (*Keyring, error) {
+	k := new(Keyring)
+	for _, id := range identities {
+		if err := k.Add(id); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// LoadKeyring reads a JSON file holding a list of identities, or a directory where every
+// .json file holds an identity and every .pem or .pub file the key of the identity named after
+// the file.
+func LoadKeyring(path string) (*Keyring, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var identities []*Identity
+		if err := json.Unmarshal(bs, &identities); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return NewKeyring(identities...)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	k := new(Keyring)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".pem" && ext != ".pub") {
+			continue
+		}
+		bs, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		id := new(Identity)
+		if ext == ".json" {
+			err = json.Unmarshal(bs, id)
+		} else {
+			id.Name = strings.TrimSuffix(entry.Name(), ext)
+			id.Key, err = ImportPublicKey(id.Name, bs)
+		}
+		if err == nil {
+			err = k.Add(id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+	return k, nil
+}
+
+// Save writes the identities to a JSON file readable by LoadKeyring.
+func (k *Keyring) Save(path string) error {
+	bs, err := json.MarshalIndent(k.identities, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bs, 0644)
+}
+
+func (k *Keyring) Add(id *Identity) error {
+	if len(id.Name) == 0 || id.Key == nil {
+		return errors.New("identity needs a name and a key")
+	}
+	for _, v := range k.identities {
+		if v.Name == id.Name {
+			return fmt.Errorf("duplicate identity %q", id.Name)
+		}
+		if v.Key.Equal(id.Key) {
+			return fmt.Errorf("identities %q and %q share key %s", v.Name, id.Name, id.Key.Fingerprint())
+		}
+	}
+	k.identities = append(k.identities, id)
+	return nil
+}
+
+func (k *Keyring) Identities() []*Identity {
+	return append([]*Identity(nil), k.identities...)
+}
+
+// Identity returns the identity called name, nil if unknown.
+func (k *Keyring) Identity(name string) *Identity {
+	for _, v := range k.identities {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// FindKey returns the identity of a PKIX key, nil if unknown.
+func (k *Keyring) FindKey(pkix []byte) *Identity {
+	key, err := NewParsedPublicKey("", pkix)
+	if err != nil {
+		return nil
+	}
+	return k.findKey(key)
+}
+
+func (k *Keyring) findKey(key *ParsedPublicKey) *Identity {
+	for _, v := range k.identities {
+		if v.Key.Equal(key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Resolve looks up a key reference, the name of an identity or the hex fingerprint of its key.
+func (k *Keyring) Resolve(ref string) (*Identity, error) {
+	if id := k.Identity(ref); id != nil {
+		return id, nil
+	}
+	for _, v := range k.identities {
+		if strings.EqualFold(v.Key.Fingerprint().String(), ref) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key reference %q", ref)
+}
+
+// Group builds an AccessGroup from key references, keys revoked at t are rejected.
+func (k *Keyring) Group(name string, quorum int, t time.Time, refs ...string) (*AccessGroup, error) {
+	group := &AccessGroup{Name: name, Quorum: quorum}
+	for _, ref := range refs {
+		id, err := k.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		if id.IsRevoked(t) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyRevoked, id.Name)
+		}
+		group.PublicKeys = append(group.PublicKeys, id.Key)
+	}
+	return group, group.Validate()
+}
+
+// CheckAccess reports keys of a which aren't in the keyring, are revoked at t, or carry a name
+// which differs from their identity.
+func (k *Keyring) CheckAccess(a *Access, t time.Time) error {
+	return k.forEachKey(a, func(group *AccessGroup, i int, id *Identity) error {
+		if id == nil {
+			return fmt.Errorf("group %q, key %d: %w", group.Name, i, ErrUnknownSigner)
+		}
+		if id.IsRevoked(t) {
+			return fmt.Errorf("group %q, key %d: %w: %s", group.Name, i, ErrKeyRevoked, id.Name)
+		}
+		if named, ok := group.PublicKeys[i].(NamedPublicKey); ok && len(named.GetName()) > 0 && named.GetName() != id.Name {
+			return fmt.Errorf("group %q, key %d: named %q but belongs to %q", group.Name, i, named.GetName(), id.Name)
+		}
+		return nil
+	})
+}
+
+// NameAccess replaces the keys of a found in the keyring by their identity, so they're shown and
+// serialized with the identity name.
+func (k *Keyring) NameAccess(a *Access) {
+	_ = k.forEachKey(a, func(group *AccessGroup, i int, id *Identity) error {
+		if id != nil {
+			group.PublicKeys[i] = NewPublicKeyImpl(id.Name, group.PublicKeys[i].GetEncoded())
+		}
+		return nil
+	})
+}
+
+func (k *Keyring) forEachKey(a *Access, fn func(group *AccessGroup, i int, id *Identity) error) error {
+	for _, blob := range a.Blobs() {
+		for _, token := range blob {
+			for _, group := range token.Groups {
+				for i, publicKey := range group.PublicKeys {
+					if err := fn(group, i, k.FindKey(publicKey.GetEncoded())); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
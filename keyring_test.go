@@ -0,0 +1,80 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"github.com/samber/lo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyring(t *testing.T) {
+	alice := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	bob := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	mallory := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	aliceKey := lo.Must(NewParsedPublicKeyFromKey("alice", &alice.PublicKey))
+	bobKey := lo.Must(NewParsedPublicKeyFromKey("bob", &bob.PublicKey))
+
+	dir := t.TempDir()
+	revoked := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	aliceJson := lo.Must(json.Marshal(&Identity{Name: "alice", Owner: "Alice", Device: "YubiKey", Enrolled: revoked.AddDate(-1, 0, 0), Key: aliceKey}))
+	lo.Must0(os.WriteFile(filepath.Join(dir, "alice.json"), aliceJson, 0644))
+	lo.Must0(os.WriteFile(filepath.Join(dir, "bob.pem"), lo.Must(bobKey.Export(PublicKeyFormats.PEM)), 0644))
+
+	keyring := lo.Must(LoadKeyring(dir))
+	if len(keyring.Identities()) != 2 || keyring.Identity("alice").Owner != "Alice" || keyring.Identity("bob") == nil {
+		t.Fatal("invalid keyring")
+	}
+	if lo.Must(keyring.Resolve(bobKey.Fingerprint().String())).Name != "bob" {
+		t.Fatal("invalid fingerprint reference")
+	}
+	keyring.Identity("bob").Revoked = revoked
+
+	file := filepath.Join(dir, "keyring.txt")
+	lo.Must0(keyring.Save(file))
+	keyring = lo.Must(LoadKeyring(file))
+	if !keyring.Identity("bob").Revoked.Equal(revoked) {
+		t.Fatal("invalid saved keyring")
+	}
+
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "key")
+	opts := &VerifyOptions{Keyring: keyring}
+	verified, err := VerifyAuthorizationToken(lo.Must(approval.Authorize(alice, EcdsaSignAlg.SHA256withECDSA)).GetEncoding(), opts)
+	if err != nil || verified.Identity != "alice" {
+		t.Fatal("invalid identity", err)
+	}
+	if _, err := VerifyAuthorizationToken(lo.Must(approval.Authorize(bob, EcdsaSignAlg.SHA256withECDSA)).GetEncoding(), opts); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatal("expected revoked key, got", err)
+	}
+	opts.Now = func() time.Time { return revoked.Add(-time.Hour) }
+	lo.Must(VerifyAuthorizationToken(lo.Must(approval.Authorize(bob, EcdsaSignAlg.SHA256withECDSA)).GetEncoding(), opts))
+	if _, err := VerifyAuthorizationToken(lo.Must(approval.Authorize(mallory, EcdsaSignAlg.SHA256withECDSA)).GetEncoding(), opts); !errors.Is(err, ErrUnknownSigner) {
+		t.Fatal("expected unknown signer, got", err)
+	}
+
+	// names in policies are checked against the keyring
+	group := &AccessGroup{Name: "approvers", Quorum: 1, PublicKeys: []Publickey{NewPublicKeyImpl("bob", aliceKey.GetEncoded())}}
+	tokens := []*AccessToken{{Name: "default", Groups: []*AccessGroup{group}}}
+	access := NewAccess(tokens, nil, nil, nil)
+	if err := keyring.CheckAccess(access, revoked.Add(-time.Hour)); err == nil || !strings.Contains(err.Error(), `belongs to "alice"`) {
+		t.Fatal("expected name mismatch, got", err)
+	}
+	keyring.NameAccess(access)
+	lo.Must0(keyring.CheckAccess(access, revoked.Add(-time.Hour)))
+	if !strings.Contains(string(lo.Must(json.Marshal(group))), `"key_names":["alice"]`) {
+		t.Fatal("names must be shown")
+	}
+
+	if _, err := keyring.Group("approvers", 1, revoked, "alice", "bob"); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatal("expected revoked key, got", err)
+	}
+	if group := lo.Must(keyring.Group("approvers", 1, revoked.Add(-time.Hour), "alice", "bob")); len(group.PublicKeys) != 2 {
+		t.Fatal("keys are valid before revocation", group)
+	}
+}
@@ -0,0 +1,263 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"math/big"
+	"os"
+)
+
+var ErrKeystorePassphrase = errors.New("keystore: wrong passphrase or corrupted keystore")
+
+var KeystoreKDFs = struct {
+	Scrypt   string
+	Argon2id string
+}{
+	Scrypt:   "scrypt",
+	Argon2id: "argon2id",
+}
+
+const keystoreVersion = 1
+const keystoreCipher = "xchacha20-poly1305"
+
+// KeystoreOptions selects the KDF of a keystore and its cost, zero values take the defaults.
+type KeystoreOptions struct {
+	KDF string // defaults to scrypt
+	// scrypt
+	ScryptN int // defaults to 1<<15
+	// argon2id
+	Argon2Time    uint32 // defaults to 3
+	Argon2Memory  uint32 // KiB, defaults to 64 MiB
+	Argon2Threads uint8  // defaults to 4
+}
+
+type KeystoreKDF struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// Keystore is a private approver key encrypted with a passphrase. The private scalar or
+// Ed25519 seed is encrypted, the label, key algorithm and public key are authenticated.
+type Keystore struct {
+	Version    int         `json:"version"`
+	Label      string      `json:"label"`
+	KeyAlg     KeyAlgT     `json:"key_alg"`
+	PublicKey  []byte      `json:"public_key"` // PKIX
+	KDF        KeystoreKDF `json:"kdf"`
+	Cipher     string      `json:"cipher"`
+	Nonce      []byte      `json:"nonce"`
+	Ciphertext []byte      `json:"ciphertext"`
+}
+
+// NewKeystore encrypts an ECDSA, secp256k1 or Ed25519 private key.
+func NewKeystore(label string, priv crypto.Signer, passphrase []byte, opts *KeystoreOptions) (*Keystore, error) {
+	var secret []byte
+	switch key := priv.(type) {
+	case *ecdsa.PrivateKey:
+		if _, err := KeyAlgOf(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		secret = key.D.FillBytes(make([]byte, curveOrderSize(key.Curve)))
+	case *Secp256k1Signer:
+		secret = key.Bytes()
+	case ed25519.PrivateKey:
+		secret = key.Seed()
+	default:
+		return nil, fmt.Errorf("keystore: unsupported private key %T", priv)
+	}
+	keyAlg, err := KeyAlgOf(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := marshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	k := &Keystore{Version: keystoreVersion, Label: label, KeyAlg: keyAlg, PublicKey: publicKey, Cipher: keystoreCipher}
+	return k, k.seal(secret, passphrase, opts)
+}
+
+func LoadKeystore(path string) (*Keystore, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeystore(bs)
+}
+
+func ParseKeystore(bs []byte) (*Keystore, error) {
+	k := new(Keystore)
+	if err := json.Unmarshal(bs, k); err != nil {
+		return nil, err
+	}
+	if k.Version != keystoreVersion || k.Cipher != keystoreCipher {
+		return nil, fmt.Errorf("keystore: unsupported version %d or cipher %q", k.Version, k.Cipher)
+	}
+	return k, nil
+}
+
+// Save writes the keystore readable by the owner only.
+func (k *Keystore) Save(path string) error {
+	bs, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bs, 0600)
+}
+
+// ParsedPublicKey returns the public key, named with the label, e.g. for enrollment in a Keyring.
+func (k *Keystore) ParsedPublicKey() (*ParsedPublicKey, error) {
+	return NewParsedPublicKey(k.Label, k.PublicKey)
+}
+
+// Unlock decrypts the private key into a signer usable with ApprovalToken.Authorize.
+func (k *Keystore) Unlock(passphrase []byte) (crypto.Signer, error) {
+	secret, err := k.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	var signer crypto.Signer
+	switch k.KeyAlg {
+	case KeyAlg.ED25519:
+		if len(secret) != ed25519.SeedSize {
+			return nil, ErrKeystorePassphrase
+		}
+		signer = ed25519.NewKeyFromSeed(secret)
+	case KeyAlg.SECP256K1:
+		if signer, err = NewSecp256k1Signer(secret); err != nil {
+			return nil, err
+		}
+	default:
+		curve := k.KeyAlg.Curve()
+		if curve == nil {
+			return nil, fmt.Errorf("keystore: unsupported key algorithm %s", k.KeyAlg)
+		}
+		priv := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(secret)}
+		if priv.D.Sign() == 0 || priv.D.Cmp(curve.Params().N) >= 0 {
+			return nil, errors.New("keystore: invalid private key")
+		}
+		priv.Curve = curve
+		priv.X, priv.Y = curve.ScalarBaseMult(secret)
+		signer = priv
+	}
+	publicKey, err := marshalPKIXPublicKey(signer.Public())
+	if err != nil || !samePublicKey(publicKey, k.PublicKey) {
+		return nil, errors.New("keystore: private key doesn't match the public key")
+	}
+	return signer, nil
+}
+
+// ChangePassphrase re-encrypts the key with a new passphrase and a fresh salt, using opts for
+// the KDF.
+func (k *Keystore) ChangePassphrase(oldPassphrase, newPassphrase []byte, opts *KeystoreOptions) error {
+	secret, err := k.open(oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return k.seal(secret, newPassphrase, opts)
+}
+
+func (k *Keystore) seal(secret, passphrase []byte, opts *KeystoreOptions) error {
+	if opts == nil {
+		opts = new(KeystoreOptions)
+	}
+	kdf := KeystoreKDF{Name: opts.KDF, Salt: make([]byte, 16)}
+	if _, err := rand.Read(kdf.Salt); err != nil {
+		return err
+	}
+	switch kdf.Name {
+	case "", KeystoreKDFs.Scrypt:
+		kdf.Name, kdf.N, kdf.R, kdf.P = KeystoreKDFs.Scrypt, 1<<15, 8, 1
+		if opts.ScryptN > 0 {
+			kdf.N = opts.ScryptN
+		}
+	case KeystoreKDFs.Argon2id:
+		kdf.Time, kdf.Memory, kdf.Threads = 3, 64*1024, 4
+		if opts.Argon2Time > 0 {
+			kdf.Time = opts.Argon2Time
+		}
+		if opts.Argon2Memory > 0 {
+			kdf.Memory = opts.Argon2Memory
+		}
+		if opts.Argon2Threads > 0 {
+			kdf.Threads = opts.Argon2Threads
+		}
+	default:
+		return fmt.Errorf("keystore: unknown KDF %q", opts.KDF)
+	}
+	key, err := kdf.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	k.KDF, k.Nonce = kdf, nonce
+	k.Ciphertext = aead.Seal(nil, nonce, secret, k.additionalData())
+	return nil
+}
+
+func (k *Keystore) open(passphrase []byte) ([]byte, error) {
+	key, err := k.KDF.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(k.Nonce) != aead.NonceSize() {
+		return nil, ErrKeystorePassphrase
+	}
+	secret, err := aead.Open(nil, k.Nonce, k.Ciphertext, k.additionalData())
+	if err != nil {
+		return nil, ErrKeystorePassphrase
+	}
+	return secret, nil
+}
+
+// additionalData binds the clear text fields to the ciphertext.
+func (k *Keystore) additionalData() []byte {
+	var ad []byte
+	for _, v := range [][]byte{{byte(k.Version)}, []byte(k.Label), []byte(k.KeyAlg), k.PublicKey} {
+		ad = binary.BigEndian.AppendUint32(ad, uint32(len(v)))
+		ad = append(ad, v...)
+	}
+	return ad
+}
+
+func (kdf *KeystoreKDF) deriveKey(passphrase []byte) ([]byte, error) {
+	if len(kdf.Salt) < 16 {
+		return nil, errors.New("keystore: salt too short")
+	}
+	switch kdf.Name {
+	case KeystoreKDFs.Scrypt:
+		return scrypt.Key(passphrase, kdf.Salt, kdf.N, kdf.R, kdf.P, chacha20poly1305.KeySize)
+	case KeystoreKDFs.Argon2id:
+		if kdf.Time == 0 || kdf.Memory == 0 || kdf.Threads == 0 {
+			return nil, errors.New("keystore: invalid argon2id parameters")
+		}
+		return argon2.IDKey(passphrase, kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, chacha20poly1305.KeySize), nil
+	}
+	return nil, fmt.Errorf("keystore: unknown KDF %q", kdf.Name)
+}
@@ -0,0 +1,55 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/samber/lo"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	ecKey := lo.Must(ecdsa.GenerateKey(elliptic.P521(), rand.Reader))
+	_, edKey := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	fast := &KeystoreOptions{ScryptN: 1 << 10}
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "key")
+
+	for _, v := range []struct {
+		priv crypto.Signer
+		alg  SignAlgT
+		opts *KeystoreOptions
+	}{
+		{ecKey, EcdsaSignAlg.SHA512withECDSA, fast},
+		{lo.Must(GenerateSecp256k1Signer()), EcdsaSignAlg.SHA256withECDSA, &KeystoreOptions{KDF: KeystoreKDFs.Argon2id, Argon2Memory: 1024, Argon2Time: 1}},
+		{edKey, EddsaSignAlg.Ed25519, fast},
+	} {
+		path := filepath.Join(t.TempDir(), "approver.json")
+		lo.Must0(lo.Must(NewKeystore("alice laptop", v.priv, []byte("secret"), v.opts)).Save(path))
+
+		keystore := lo.Must(LoadKeystore(path))
+		if _, err := keystore.Unlock([]byte("wrong")); !errors.Is(err, ErrKeystorePassphrase) {
+			t.Fatal("expected passphrase error, got", err)
+		}
+		signer := lo.Must(keystore.Unlock([]byte("secret")))
+		verified := lo.Must(VerifyAuthorizationToken(lo.Must(approval.Authorize(signer, v.alg)).GetEncoding(), nil))
+		enrolled := lo.Must(keystore.ParsedPublicKey())
+		if !verified.Key.Equal(enrolled) || enrolled.GetName() != "alice laptop" || enrolled.KeyAlg() != keystore.KeyAlg {
+			t.Fatal("invalid public key", keystore.KeyAlg)
+		}
+
+		lo.Must0(keystore.ChangePassphrase([]byte("secret"), []byte("new secret"), fast))
+		if _, err := keystore.Unlock([]byte("secret")); !errors.Is(err, ErrKeystorePassphrase) {
+			t.Fatal("old passphrase must not unlock the keystore")
+		}
+		lo.Must(keystore.Unlock([]byte("new secret")))
+
+		keystore.Label = "mallory laptop"
+		if _, err := keystore.Unlock([]byte("new secret")); !errors.Is(err, ErrKeystorePassphrase) {
+			t.Fatal("label must be authenticated")
+		}
+	}
+}
@@ -13,6 +13,20 @@ type Payload struct {
 	_size int
 }
 
+// DecodePayload decodes data with or without the leading length header.
+func DecodePayload(data []byte) (*Payload, error) {
+	return optionallyCutLengthHeaderDecodePayload(data)
+}
+
+func (p *Payload) Parts() []PayloadPart {
+	return p.parts
+}
+
+// Find returns the first part of type typ, nil if there is none.
+func (p *Payload) Find(typ PayloadType) *PayloadPart {
+	return p.find(typ)
+}
+
 func (p *Payload) add(part *PayloadPart) {
 	p._size += part.Size()
 	p.parts = append(p.parts, *part)
@@ -96,6 +110,10 @@ func NewPayloadPartInt(typ PayloadType, data int) *PayloadPart {
 	return NewPayloadPart(typ, LEUint32(data))
 }
 
+func (p *PayloadPart) Type() PayloadType {
+	return p.typ
+}
+
 func (p *PayloadPart) Data() []byte {
 	return p.data
 }
@@ -125,25 +143,29 @@ func (p *PayloadPart) Deserialize(buf io.Reader) error {
 		return err
 	}
 	p.typ = PayloadType(typ)
-	var length int16
-	if err := binary.Read(buf, binary.LittleEndian, &length); err != nil {
+	var length16 uint16
+	if err := binary.Read(buf, binary.LittleEndian, &length16); err != nil {
 		return err
 	}
-	if length == -1 {
-		var extendedLength int32
+	var length = int(length16)
+	if length16 == 0xffff {
+		var extendedLength uint32
 		if err := binary.Read(buf, binary.LittleEndian, &extendedLength); err != nil {
 			return err
 		}
-		length = int16(extendedLength)
+		length = int(extendedLength)
+	}
+	if r, ok := buf.(interface{ Len() int }); ok && length > r.Len() {
+		return fmt.Errorf("payload part %d: length %d exceeds remaining %d bytes", typ, length, r.Len())
 	}
 	p.data = make([]byte, length)
-	if _, err := buf.Read(p.data); err != nil {
+	if _, err := io.ReadFull(buf, p.data); err != nil {
 		return err
 	}
-	pad := padding(int(length))
+	pad := padding(length)
 	if pad > 0 {
 		paddingBytes := make([]byte, pad)
-		if _, err := buf.Read(paddingBytes); err != nil {
+		if _, err := io.ReadFull(buf, paddingBytes); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,41 @@
+package primus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPayloadLongParts(t *testing.T) {
+	var p = new(Payload)
+	p.addBs(EKA_SIGN_PAYLOAD, bytes.Repeat([]byte{1}, 40000))
+	p.addBs(EKA_MODIFY_PAYLOAD, bytes.Repeat([]byte{2}, 70001))
+	p.addInt(EKA_OPERATION, 1)
+	decoded := new(Payload)
+	if err := decoded.Deserialize(p.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.parts) != 3 {
+		t.Fatal("unexpected parts", len(decoded.parts))
+	}
+	for i, part := range p.parts {
+		if decoded.parts[i].typ != part.typ || !bytes.Equal(decoded.parts[i].data, part.data) {
+			t.Fatal("part doesn't round trip", i, len(decoded.parts[i].data))
+		}
+	}
+}
+
+func TestPayloadTruncated(t *testing.T) {
+	var p = new(Payload)
+	p.addBs(EKA_SIGN_PAYLOAD, []byte("content to be sign"))
+	p.addBs(EKA_MODIFY_PAYLOAD, bytes.Repeat([]byte{2}, 70000))
+	data := p.Bytes()
+	for _, n := range []int{1, 3, 10, 22, 26, 30, len(data) - 70000, len(data) - 1} {
+		if err := new(Payload).Deserialize(data[:n]); err == nil {
+			t.Fatal("expected error for truncated payload of", n, "bytes")
+		}
+	}
+	// a length beyond the data must not allocate it
+	if err := new(Payload).Deserialize([]byte{0x57, 0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}); err == nil {
+		t.Fatal("expected error for oversized length")
+	}
+}
@@ -0,0 +1,113 @@
+package primus
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// policyJSON is the file form of an Access, the same as its JSON encoding.
+type policyJSON struct {
+	Sign    []*policyTokenJSON `json:"sign"`
+	Block   []*policyTokenJSON `json:"block"`
+	UnBlock []*policyTokenJSON `json:"unblock"`
+	Modify  []*policyTokenJSON `json:"modify"`
+}
+
+type policyTokenJSON struct {
+	Name        string             `json:"name"`
+	DelayMs     int64              `json:"delay_ms"`
+	TimeLimitMs int64              `json:"time_limit_ms"`
+	Groups      []*policyGroupJSON `json:"groups"`
+}
+
+type policyGroupJSON struct {
+	Name       string   `json:"name"`
+	Quorum     int      `json:"quorum"`
+	PublicKeys []string `json:"public_keys"`
+	KeyNames   []string `json:"key_names"`
+}
+
+// ParsePolicy reads an Access from its JSON encoding. Keys may be given as hex DER, in any
+// format of ImportPublicKey or, with keyring, which may be nil, by the name or fingerprint of an
+// identity. Every group is validated.
+func ParsePolicy(data []byte, keyring *Keyring) (*Access, error) {
+	var policy policyJSON
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	var access Access
+	for _, blob := range []struct {
+		name   BlobName
+		tokens []*policyTokenJSON
+		target *[]*AccessToken
+	}{
+		{BlobNames.Signing, policy.Sign, &access.Sign},
+		{BlobNames.Block, policy.Block, &access.Block},
+		{BlobNames.UnBlock, policy.UnBlock, &access.UnBlock},
+		{BlobNames.Modify, policy.Modify, &access.Modify},
+	} {
+		for i, t := range blob.tokens {
+			token, err := t.build(keyring)
+			if err != nil {
+				return nil, fmt.Errorf("%s token %d: %w", blob.name, i, err)
+			}
+			*blob.target = append(*blob.target, token)
+		}
+	}
+	return &access, nil
+}
+
+func (t *policyTokenJSON) build(keyring *Keyring) (*AccessToken, error) {
+	if t.DelayMs < 0 || t.TimeLimitMs < 0 {
+		return nil, errors.New("negative delay or time limit")
+	}
+	token := &AccessToken{Name: t.Name, DelayMs: t.DelayMs, TimeLimitMs: t.TimeLimitMs}
+	for _, g := range t.Groups {
+		group, err := g.build(keyring)
+		if err != nil {
+			return nil, err
+		}
+		token.Groups = append(token.Groups, group)
+	}
+	return token, nil
+}
+
+func (g *policyGroupJSON) build(keyring *Keyring) (*AccessGroup, error) {
+	if len(g.KeyNames) > 0 && len(g.KeyNames) != len(g.PublicKeys) {
+		return nil, fmt.Errorf("group %q has %d key names for %d keys", g.Name, len(g.KeyNames), len(g.PublicKeys))
+	}
+	group := &AccessGroup{Name: g.Name, Quorum: g.Quorum}
+	for i, ref := range g.PublicKeys {
+		var name string
+		if len(g.KeyNames) > 0 {
+			name = g.KeyNames[i]
+		}
+		key, err := resolvePolicyKey(name, ref, keyring)
+		if err != nil {
+			return nil, fmt.Errorf("group %q, key %d: %w", g.Name, i, err)
+		}
+		group.PublicKeys = append(group.PublicKeys, key)
+	}
+	return group, group.Validate()
+}
+
+func resolvePolicyKey(name, ref string, keyring *Keyring) (Publickey, error) {
+	keyData, err := hex.DecodeString(ref)
+	if err != nil {
+		keyData = []byte(ref)
+	}
+	key, err := ImportPublicKey(name, keyData)
+	if err == nil {
+		return key, nil
+	}
+	if keyring == nil {
+		return nil, err
+	}
+	id, resolveErr := keyring.Resolve(ref)
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	return NewPublicKeyImpl(nameOr(name, id.Name), id.Key.GetEncoded()), nil
+}
@@ -0,0 +1,178 @@
+package primus
+
+import (
+	"fmt"
+	"github.com/samber/lo"
+	"math"
+	"time"
+)
+
+// PolicyChange is a difference between two Access found by DiffAccess.
+type PolicyChange struct {
+	Blob    string `json:"blob"`
+	Token   string `json:"token,omitempty"`
+	Message string `json:"message"`
+}
+
+func (c PolicyChange) String() string {
+	where := c.Blob
+	if len(c.Token) > 0 {
+		where += "/" + c.Token
+	}
+	return where + ": " + c.Message
+}
+
+type AccessDiff struct {
+	Changes []PolicyChange `json:"changes"`
+	// Weakened are the blobs of the new Access with a token which is easier to satisfy than every
+	// token of the old blob, or which lost all tokens, see LintAccess.
+	Weakened []string `json:"weakened"`
+}
+
+func (d *AccessDiff) Weakens(name BlobName) bool {
+	return lo.Contains(d.Weakened, name.String())
+}
+
+// DiffAccess compares the tokens of each blob, matched by name or else by position.
+func DiffAccess(from, to *Access) *AccessDiff {
+	diff := &AccessDiff{Changes: []PolicyChange{}, Weakened: []string{}}
+	toBlobs := to.Blobs()
+	for i, fromBlob := range from.Blobs() {
+		name, toBlob := accessBlobNames[i], toBlobs[i]
+		report := func(token string, format string, args ...any) {
+			diff.Changes = append(diff.Changes, PolicyChange{Blob: name.String(), Token: token, Message: fmt.Sprintf(format, args...)})
+		}
+		matched := make(map[int]bool)
+		for j, fromToken := range fromBlob {
+			ref := tokenRef(fromToken, j)
+			k := findToken(toBlob, fromToken, j)
+			if k < 0 || matched[k] {
+				report(ref, "token removed")
+				continue
+			}
+			matched[k] = true
+			diffToken(fromToken, toBlob[k], func(format string, args ...any) {
+				report(ref, format, args...)
+			})
+		}
+		for k, toToken := range toBlob {
+			if !matched[k] {
+				report(tokenRef(toToken, k), "token added")
+			}
+		}
+		weaken := func() {
+			if !diff.Weakens(name) {
+				diff.Weakened = append(diff.Weakened, name.String())
+			}
+		}
+		if len(toBlob) == 0 && len(fromBlob) > 0 {
+			report("", "blob has no token anymore")
+			weaken()
+		}
+		for k, toToken := range toBlob {
+			if !lo.ContainsBy(fromBlob, func(fromToken *AccessToken) bool { return tokenImplies(toToken, fromToken) }) {
+				report(tokenRef(toToken, k), "token is weaker than every former token")
+				weaken()
+			}
+		}
+	}
+	return diff
+}
+
+func findToken(blob AccessBlob, token *AccessToken, i int) int {
+	if len(token.Name) > 0 {
+		_, k, _ := lo.FindIndexOf(blob, func(item *AccessToken) bool { return item.Name == token.Name })
+		return k
+	}
+	if i < len(blob) && len(blob[i].Name) == 0 {
+		return i
+	}
+	return -1
+}
+
+func diffToken(from, to *AccessToken, report func(format string, args ...any)) {
+	if from.DelayMs != to.DelayMs {
+		report("delay %s -> %s", time.Duration(from.DelayMs)*time.Millisecond, time.Duration(to.DelayMs)*time.Millisecond)
+	}
+	if from.TimeLimitMs != to.TimeLimitMs {
+		report("time limit %s -> %s", describeTimeLimit(from.TimeLimitMs), describeTimeLimit(to.TimeLimitMs))
+	}
+	for i, fromGroup := range from.Groups {
+		var toGroup *AccessGroup
+		if k := findGroup(to.Groups, fromGroup, i); k >= 0 {
+			toGroup = to.Groups[k]
+		}
+		if toGroup == nil {
+			report("group %q removed", fromGroup.Name)
+			continue
+		}
+		if fromGroup.Quorum != toGroup.Quorum {
+			report("group %q quorum %d -> %d", fromGroup.Name, fromGroup.Quorum, toGroup.Quorum)
+		}
+		for _, key := range fromGroup.PublicKeys {
+			if toGroup.Count([][]byte{key.GetEncoded()}) == 0 {
+				report("group %q key removed: %s", fromGroup.Name, describePublicKey(key))
+			}
+		}
+		for _, key := range toGroup.PublicKeys {
+			if fromGroup.Count([][]byte{key.GetEncoded()}) == 0 {
+				report("group %q key added: %s", fromGroup.Name, describePublicKey(key))
+			}
+		}
+	}
+	for i, toGroup := range to.Groups {
+		if findGroup(from.Groups, toGroup, i) < 0 {
+			report("group %q added", toGroup.Name)
+		}
+	}
+}
+
+func findGroup(groups []*AccessGroup, group *AccessGroup, i int) int {
+	if len(group.Name) > 0 {
+		_, k, _ := lo.FindIndexOf(groups, func(item *AccessGroup) bool { return item.Name == group.Name })
+		return k
+	}
+	if i < len(groups) && len(groups[i].Name) == 0 {
+		return i
+	}
+	return -1
+}
+
+// tokenImplies reports whether approvals satisfying a also satisfy b: a doesn't release sooner
+// or for longer, and every group of b is implied by a group of a with a subset of its keys and
+// at least its quorum.
+func tokenImplies(a, b *AccessToken) bool {
+	if a.DelayMs < b.DelayMs || timeLimit(a.TimeLimitMs) > timeLimit(b.TimeLimitMs) {
+		return false
+	}
+	for _, gb := range b.Groups {
+		if !lo.ContainsBy(a.Groups, func(ga *AccessGroup) bool {
+			return ga.Quorum >= gb.Quorum && gb.Count(lo.Map(ga.PublicKeys, func(item Publickey, _ int) []byte {
+				return item.GetEncoded()
+			})) == len(ga.PublicKeys)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// timeLimit returns the time limit of a token in ms, a limit of 0 never expires.
+func timeLimit(ms int64) int64 {
+	return lo.Ternary(ms == 0, math.MaxInt64, ms)
+}
+
+func describeTimeLimit(ms int64) string {
+	if ms == 0 {
+		return "unlimited"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+func describePublicKey(publicKey Publickey) string {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Sprintf("%x", publicKey.GetEncoded())
+	}
+	return lo.Ternary(len(key.GetName()) > 0, key.GetName()+" ", "") + key.Fingerprint().String()
+}
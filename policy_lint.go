@@ -0,0 +1,187 @@
+package primus
+
+import (
+	"fmt"
+	"github.com/samber/lo"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PolicySeverity int
+
+var PolicySeverities = struct {
+	Low    PolicySeverity
+	Medium PolicySeverity
+	High   PolicySeverity
+}{
+	Low:    1,
+	Medium: 2,
+	High:   3,
+}
+
+func (s PolicySeverity) String() string {
+	switch s {
+	case PolicySeverities.Low:
+		return "low"
+	case PolicySeverities.Medium:
+		return "medium"
+	case PolicySeverities.High:
+		return "high"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+func (s *PolicySeverity) FromString(str string) bool {
+	for _, v := range []PolicySeverity{PolicySeverities.Low, PolicySeverities.Medium, PolicySeverities.High} {
+		if strings.EqualFold(v.String(), str) {
+			*s = v
+			return true
+		}
+	}
+	return false
+}
+
+func (s PolicySeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// PolicyFinding is an issue of an Access found by LintAccess.
+type PolicyFinding struct {
+	Severity PolicySeverity `json:"severity"`
+	Blob     string         `json:"blob"`
+	Token    string         `json:"token,omitempty"`
+	Message  string         `json:"message"`
+}
+
+func (f PolicyFinding) String() string {
+	where := f.Blob
+	if len(f.Token) > 0 {
+		where += "/" + f.Token
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Severity, where, f.Message)
+}
+
+type LintOptions struct {
+	// Keyring enables checks of the keys against the approver identities.
+	Keyring *Keyring
+	// Now is the time keys must not be revoked at, defaults to time.Now.
+	Now time.Time
+}
+
+// LintAccess reports weaknesses and mistakes of a, most severe first.
+func LintAccess(a *Access, opts *LintOptions) []PolicyFinding {
+	if opts == nil {
+		opts = new(LintOptions)
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var findings []PolicyFinding
+	report := func(severity PolicySeverity, blob BlobName, token string, format string, args ...any) {
+		findings = append(findings, PolicyFinding{Severity: severity, Blob: blob.String(), Token: token, Message: fmt.Sprintf(format, args...)})
+	}
+	for i, blob := range a.Blobs() {
+		name := accessBlobNames[i]
+		if len(blob) == 0 {
+			report(lo.Ternary(name == BlobNames.Modify, PolicySeverities.High, PolicySeverities.Medium), name, "", "blob has no token")
+		}
+		seen := make(map[string]bool)
+		for j, token := range blob {
+			ref := tokenRef(token, j)
+			if len(token.Name) > 0 {
+				if seen[token.Name] {
+					report(PolicySeverities.Low, name, ref, "duplicate token name")
+				}
+				seen[token.Name] = true
+			}
+			if len(token.Groups) == 0 {
+				report(PolicySeverities.High, name, ref, "token requires no approval")
+				continue
+			}
+			if token.TimeLimitMs == 0 {
+				report(PolicySeverities.Medium, name, ref, "no time limit, approvals never expire")
+			}
+			if name == BlobNames.Modify {
+				if approvals(token) == 1 {
+					report(PolicySeverities.High, name, ref, "a single approver can change the policy")
+				}
+				if token.DelayMs == 0 {
+					report(PolicySeverities.Low, name, ref, "no delay to block a policy change")
+				}
+			}
+			lintGroups(token, opts.Keyring, now, func(severity PolicySeverity, format string, args ...any) {
+				report(severity, name, ref, format, args...)
+			})
+		}
+	}
+	if len(a.Modify) > 0 && len(a.Sign) > 0 && minApprovals(a.Modify) < minApprovals(a.Sign) {
+		report(PolicySeverities.High, BlobNames.Modify, "", "needs %d approvals while Signing needs %d, Signing can be bypassed by changing the policy",
+			minApprovals(a.Modify), minApprovals(a.Sign))
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+	return findings
+}
+
+func lintGroups(token *AccessToken, keyring *Keyring, now time.Time, report func(severity PolicySeverity, format string, args ...any)) {
+	owners := make(map[Fingerprint]string)
+	for _, group := range token.Groups {
+		if err := group.Validate(); err != nil {
+			report(PolicySeverities.High, "%v", err)
+		}
+		for i, publicKey := range group.PublicKeys {
+			key, err := ParsePublicKey(publicKey)
+			if err != nil {
+				continue
+			}
+			if owner, ok := owners[key.Fingerprint()]; ok && owner != group.Name {
+				report(PolicySeverities.Medium, "key %s counts towards groups %q and %q", key.Fingerprint(), owner, group.Name)
+			}
+			owners[key.Fingerprint()] = group.Name
+			if alg := key.KeyAlg(); len(alg) > 0 && len(alg.SignatureAlgorithms()) == 0 {
+				report(PolicySeverities.High, "group %q, key %d: %s keys can't sign", group.Name, i, alg)
+			}
+			if keyring == nil {
+				continue
+			}
+			id := keyring.findKey(key)
+			switch {
+			case id == nil:
+				report(PolicySeverities.Medium, "group %q, key %d: %v", group.Name, i, ErrUnknownSigner)
+			case id.IsRevoked(now):
+				report(PolicySeverities.High, "group %q, key %d: %v: %s", group.Name, i, ErrKeyRevoked, id.Name)
+			case len(key.GetName()) > 0 && key.GetName() != id.Name:
+				report(PolicySeverities.Low, "group %q, key %d: named %q but belongs to %q", group.Name, i, key.GetName(), id.Name)
+			}
+		}
+	}
+}
+
+// approvals returns the number of signatures token needs at least.
+func approvals(token *AccessToken) int {
+	var n int
+	for _, group := range token.Groups {
+		n += group.Quorum
+	}
+	return n
+}
+
+func minApprovals(blob AccessBlob) int {
+	n := -1
+	for _, token := range blob {
+		if v := approvals(token); n < 0 || v < n {
+			n = v
+		}
+	}
+	return n
+}
+
+func tokenRef(token *AccessToken, i int) string {
+	if len(token.Name) > 0 {
+		return token.Name
+	}
+	return fmt.Sprintf("#%d", i)
+}
@@ -0,0 +1,154 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/samber/lo"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPolicyTestKey(name string) *ParsedPublicKey {
+	key := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	return lo.Must(NewParsedPublicKeyFromKey(name, &key.PublicKey))
+}
+
+func TestParsePolicy(t *testing.T) {
+	alice, bob, carol := newPolicyTestKey("alice"), newPolicyTestKey("bob"), newPolicyTestKey("")
+	keyring := lo.Must(NewKeyring(&Identity{Name: "alice", Key: alice}, &Identity{Name: "bob", Key: bob}))
+	policy := fmt.Sprintf(`{
+		"sign": [{"name": "t", "delay_ms": 0, "time_limit_ms": 600000, "groups": [
+			{"name": "ops", "quorum": 2, "public_keys": ["alice", %q, %q]}
+		]}],
+		"block": [{"name": "t", "time_limit_ms": 600000, "groups": [{"name": "ops", "quorum": 1, "public_keys": [%q]}]}],
+		"unblock": [{"name": "t", "time_limit_ms": 600000, "groups": [{"name": "ops", "quorum": 1, "public_keys": [%q]}]}],
+		"modify": [{"name": "t", "delay_ms": 3600000, "time_limit_ms": 600000, "groups": [
+			{"name": "ops", "quorum": 2, "public_keys": ["alice", "bob"]}
+		]}]
+	}`, bob.Fingerprint(), lo.Must(carol.Export(PublicKeyFormats.PEM)), hex.EncodeToString(carol.GetEncoded()), lo.Must(carol.Export(PublicKeyFormats.OpenSSH)))
+
+	if _, err := ParsePolicy([]byte(policy), nil); err == nil {
+		t.Fatal("expected unresolved key reference")
+	}
+	access := lo.Must(ParsePolicy([]byte(policy), keyring))
+	group := access.Sign[0].Groups[0]
+	if len(group.PublicKeys) != 3 || group.Count([][]byte{alice.GetEncoded(), bob.GetEncoded(), carol.GetEncoded()}) != 3 {
+		t.Fatal("invalid keys")
+	}
+	if group.PublicKeys[0].(NamedPublicKey).GetName() != "alice" {
+		t.Fatal("expected key name of the identity")
+	}
+	if access.Sign[0].TimeLimitMs != 600000 || access.Modify[0].DelayMs != 3600000 {
+		t.Fatal("invalid token")
+	}
+
+	// the JSON encoding of an Access is a policy
+	decoded := new(Access)
+	lo.Must0(decoded.Deserialize(access.ToModifyPayload()))
+	again := lo.Must(ParsePolicy(lo.Must(json.Marshal(decoded)), nil))
+	if hex.EncodeToString(again.Serialize()) != hex.EncodeToString(access.Serialize()) {
+		t.Fatal("policy doesn't round trip")
+	}
+
+	if _, err := ParsePolicy([]byte(`{"sign": [{"groups": [{"quorum": 2, "public_keys": ["alice"]}]}]}`), keyring); err == nil {
+		t.Fatal("expected unreachable quorum")
+	}
+}
+
+func newPolicyTestAccess(keys ...*ParsedPublicKey) *Access {
+	token := func(delay time.Duration, quorum int) []*AccessToken {
+		return []*AccessToken{{Name: "t", DelayMs: delay.Milliseconds(), TimeLimitMs: time.Hour.Milliseconds(), Groups: []*AccessGroup{
+			{Name: "ops", Quorum: quorum, PublicKeys: lo.Map(keys, func(item *ParsedPublicKey, _ int) Publickey { return item })},
+		}}}
+	}
+	return NewAccess(token(0, 2), token(0, 1), token(0, 2), token(time.Hour, 2))
+}
+
+func TestLintAccess(t *testing.T) {
+	alice, bob, carol := newPolicyTestKey("alice"), newPolicyTestKey("bob"), newPolicyTestKey("carol")
+	access := newPolicyTestAccess(alice, bob, carol)
+	if findings := LintAccess(access, nil); len(findings) != 0 {
+		t.Fatal("unexpected findings", findings)
+	}
+
+	access.Modify[0].Groups[0].Quorum = 1
+	access.Modify[0].DelayMs = 0
+	access.Block[0].TimeLimitMs = 0
+	access.Sign[0].Groups = append(access.Sign[0].Groups, &AccessGroup{Name: "dev", Quorum: 1, PublicKeys: []Publickey{carol}})
+	findings := LintAccess(access, nil)
+	messages := strings.Join(lo.Map(findings, func(item PolicyFinding, _ int) string { return item.String() }), "\n")
+	for _, expected := range []string{
+		"high: ChangeAttributes/t: a single approver can change the policy",
+		"high: ChangeAttributes: needs 1 approvals while Signing needs 3",
+		"medium: Signing/t: key " + carol.Fingerprint().String() + ` counts towards groups "ops" and "dev"`,
+		"medium: Block/t: no time limit, approvals never expire",
+		"low: ChangeAttributes/t: no delay to block a policy change",
+	} {
+		if !strings.Contains(messages, expected) {
+			t.Fatal("missing finding", expected, "in", messages)
+		}
+	}
+	if findings[0].Severity != PolicySeverities.High || findings[len(findings)-1].Severity != PolicySeverities.Low {
+		t.Fatal("findings aren't sorted by severity")
+	}
+
+	keyring := lo.Must(NewKeyring(&Identity{Name: "alice", Key: alice}, &Identity{Name: "bob", Key: bob, Revoked: time.Now().Add(-time.Hour)}))
+	messages = fmt.Sprint(LintAccess(newPolicyTestAccess(alice, bob, carol), &LintOptions{Keyring: keyring}))
+	if !strings.Contains(messages, "high: Signing/t: group \"ops\", key 1: "+ErrKeyRevoked.Error()+": bob") ||
+		!strings.Contains(messages, "medium: Signing/t: group \"ops\", key 2: "+ErrUnknownSigner.Error()) {
+		t.Fatal("missing keyring findings", messages)
+	}
+}
+
+func TestDiffAccess(t *testing.T) {
+	alice, bob, carol := newPolicyTestKey("alice"), newPolicyTestKey("bob"), newPolicyTestKey("carol")
+	from := newPolicyTestAccess(alice, bob, carol)
+
+	diff := DiffAccess(from, newPolicyTestAccess(alice, bob, carol))
+	if len(diff.Changes) != 0 || len(diff.Weakened) != 0 {
+		t.Fatal("unexpected diff", diff)
+	}
+
+	// removing a key and raising the delay is stronger
+	to := newPolicyTestAccess(alice, bob)
+	to.Modify[0].DelayMs *= 2
+	diff = DiffAccess(from, to)
+	if diff.Weakens(BlobNames.Modify) || len(diff.Changes) != 5 {
+		t.Fatal("unexpected diff", diff.Changes)
+	}
+
+	to = newPolicyTestAccess(alice, bob, carol, newPolicyTestKey("mallory"))
+	diff = DiffAccess(from, to)
+	if !diff.Weakens(BlobNames.Modify) || !diff.Weakens(BlobNames.Signing) {
+		t.Fatal("adding a key must weaken", diff)
+	}
+	to = newPolicyTestAccess(alice, bob, carol)
+	to.Modify[0].DelayMs = 0
+	if diff = DiffAccess(from, to); !diff.Weakens(BlobNames.Modify) || diff.Weakens(BlobNames.Signing) {
+		t.Fatal("lower delay must weaken", diff)
+	}
+	to.Modify = nil
+	if diff = DiffAccess(from, to); !diff.Weakens(BlobNames.Modify) {
+		t.Fatal("empty blob must weaken", diff)
+	}
+	// a time limit of 0 never expires
+	to = newPolicyTestAccess(alice, bob, carol)
+	to.Sign[0].TimeLimitMs = 0
+	if diff = DiffAccess(from, to); !diff.Weakens(BlobNames.Signing) || diff.Changes[0].Message != "time limit 1h0m0s -> unlimited" {
+		t.Fatal("removing the time limit must weaken", diff)
+	}
+	if diff = DiffAccess(to, from); diff.Weakens(BlobNames.Signing) {
+		t.Fatal("adding a time limit must not weaken", diff)
+	}
+	// an additional, stronger alternative doesn't weaken
+	to = newPolicyTestAccess(alice, bob, carol)
+	to.Sign = append(to.Sign, &AccessToken{Name: "strict", TimeLimitMs: time.Minute.Milliseconds(), Groups: []*AccessGroup{{Quorum: 2, PublicKeys: []Publickey{alice, bob}}}})
+	if diff = DiffAccess(from, to); len(diff.Weakened) != 0 || diff.Changes[0].String() != "Signing/strict: token added" {
+		t.Fatal("unexpected diff", diff)
+	}
+}
@@ -2,10 +2,10 @@ package primus
 
 type PrimusSignature struct {
 	signature     []byte
-	signAlgorithm EcdsaSignAlgT
+	signAlgorithm SignAlgT
 }
 
-func NewPrimusSignature(signAlgorithm EcdsaSignAlgT, signature []byte) *PrimusSignature {
+func NewPrimusSignature(signAlgorithm SignAlgT, signature []byte) *PrimusSignature {
 	return &PrimusSignature{signAlgorithm: signAlgorithm, signature: signature}
 }
 
@@ -25,3 +25,12 @@ func (s *PrimusSignature) Deserialize(bs []byte) {
 		s.signature = bs
 	}
 }
+
+func (s *PrimusSignature) Signature() []byte {
+	return s.signature
+}
+
+// SignAlgorithm returns the algorithm named by the AlgorithmIdentifier, empty for a bare signature.
+func (s *PrimusSignature) SignAlgorithm() SignAlgT {
+	return s.signAlgorithm
+}
@@ -1,5 +1,23 @@
 package primus
 
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/donutnomad/blockchain-alg/xx509"
+	"math/big"
+)
+
 type Publickey interface {
 	GetEncoded() []byte // return x509 format
 }
@@ -49,3 +67,184 @@ func (p *PublicKeyImpl) DeSerialize(it *IterPart) {
 		it.Panic(one.typ, PUBLIC_KEY_ENCODED)
 	}
 }
+
+// Fingerprint is the SHA-256 hash of the canonical PKIX encoding of a public key.
+type Fingerprint [32]byte
+
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// ParsedPublicKey is a validated public key. Keys are compared by their canonical encoding, EC
+// keys with compressed and uncompressed points are equal.
+type ParsedPublicKey struct {
+	name      string
+	encoded   []byte
+	canonical []byte
+	key       crypto.PublicKey
+	keyAlg    KeyAlgT
+}
+
+// NewParsedPublicKey parses and validates a PKIX public key. EC points may be compressed, the
+// encoding is kept as is for serialization.
+func NewParsedPublicKey(name string, pkix []byte) (*ParsedPublicKey, error) {
+	pub, err := parsePublicKeyDER(pkix)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := NewParsedPublicKeyFromKey(name, pub)
+	if err != nil {
+		return nil, err
+	}
+	ret.encoded = pkix
+	return ret, nil
+}
+
+// NewParsedPublicKeyFromKey validates pub, it's encoded in the canonical form. RSA keys have no
+// KeyAlgT.
+func NewParsedPublicKeyFromKey(name string, pub crypto.PublicKey) (*ParsedPublicKey, error) {
+	var keyAlg KeyAlgT
+	if key, ok := pub.(*rsa.PublicKey); ok {
+		if key.N == nil || key.N.Sign() <= 0 || key.E < 3 || key.E%2 == 0 {
+			return nil, errors.New("invalid RSA public key")
+		}
+	} else {
+		var err error
+		if keyAlg, err = KeyAlgOf(pub); err != nil {
+			return nil, err
+		}
+	}
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key.X == nil || key.Y == nil || !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("public key is not on the curve")
+		}
+	case ed25519.PublicKey:
+		if len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key size")
+		}
+	}
+	canonical, err := marshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &ParsedPublicKey{name: name, encoded: canonical, canonical: canonical, key: pub, keyAlg: keyAlg}, nil
+}
+
+// ParsePublicKey parses a Publickey, keeping its name.
+func ParsePublicKey(pub Publickey) (*ParsedPublicKey, error) {
+	if parsed, ok := pub.(*ParsedPublicKey); ok {
+		return parsed, nil
+	}
+	var name string
+	if v, ok := pub.(NamedPublicKey); ok {
+		name = v.GetName()
+	}
+	return NewParsedPublicKey(name, pub.GetEncoded())
+}
+
+func (p *ParsedPublicKey) GetEncoded() []byte {
+	return p.encoded
+}
+
+func (p *ParsedPublicKey) GetName() string {
+	return p.name
+}
+
+func (p *ParsedPublicKey) PublicKey() crypto.PublicKey {
+	return p.key
+}
+
+func (p *ParsedPublicKey) KeyAlg() KeyAlgT {
+	return p.keyAlg
+}
+
+// Canonical returns the PKIX encoding with uncompressed EC points.
+func (p *ParsedPublicKey) Canonical() []byte {
+	return p.canonical
+}
+
+func (p *ParsedPublicKey) Fingerprint() Fingerprint {
+	return sha256.Sum256(p.canonical)
+}
+
+// Equal compares the keys, names are ignored.
+func (p *ParsedPublicKey) Equal(other *ParsedPublicKey) bool {
+	return other != nil && bytes.Equal(p.canonical, other.canonical)
+}
+
+// samePublicKey compares two PKIX keys by their canonical encoding. Keys which can't be parsed
+// only equal identical bytes.
+func samePublicKey(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	ka, err := NewParsedPublicKey("", a)
+	if err != nil {
+		return false
+	}
+	kb, err := NewParsedPublicKey("", b)
+	return err == nil && ka.Equal(kb)
+}
+
+// canonicalPublicKey returns the canonical encoding of a PKIX key, keys which can't be parsed
+// are returned as they are.
+func canonicalPublicKey(der []byte) []byte {
+	key, err := NewParsedPublicKey("", der)
+	if err != nil {
+		return der
+	}
+	return key.Canonical()
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// parsePublicKeyDER is xx509.ParsePKIXPublicKey with support for compressed EC points.
+func parsePublicKeyDER(der []byte) (crypto.PublicKey, error) {
+	pub, err := xx509.ParsePKIXPublicKey(der)
+	if err == nil {
+		return pub, nil
+	}
+	var spki subjectPublicKeyInfo
+	if rest, err2 := asn1.Unmarshal(der, &spki); err2 != nil || len(rest) > 0 || !spki.Algorithm.Algorithm.Equal(xx509.OidPublicKeyECDSA) {
+		return nil, err
+	}
+	var curveOid asn1.ObjectIdentifier
+	if rest, err2 := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curveOid); err2 != nil || len(rest) > 0 {
+		return nil, err
+	}
+	for _, alg := range keyAlgs {
+		if alg.Curve() != nil && alg.OID().Equal(curveOid) {
+			return parseECPoint(alg, spki.PublicKey.RightAlign())
+		}
+	}
+	return nil, fmt.Errorf("unsupported elliptic curve %s", curveOid)
+}
+
+// parseECPoint decodes an uncompressed or compressed SEC 1 point on the curve of alg.
+func parseECPoint(alg KeyAlgT, point []byte) (*ecdsa.PublicKey, error) {
+	curve := alg.Curve()
+	if curve == nil {
+		return nil, fmt.Errorf("%s is not an elliptic curve key algorithm", alg)
+	}
+	if alg == KeyAlg.SECP256K1 {
+		key, err := secp256k1.ParsePubKey(point)
+		if err != nil {
+			return nil, err
+		}
+		return key.ToECDSA(), nil
+	}
+	var x, y *big.Int
+	if len(point) > 0 && point[0] == 4 {
+		x, y = elliptic.Unmarshal(curve, point)
+	} else {
+		x, y = elliptic.UnmarshalCompressed(curve, point)
+	}
+	if x == nil {
+		return nil, fmt.Errorf("invalid %s point", alg)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
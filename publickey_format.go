@@ -0,0 +1,229 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"math/big"
+	"strings"
+)
+
+var PublicKeyFormats = struct {
+	DER     string
+	PEM     string
+	OpenSSH string
+	JWK     string
+	Raw     string
+}{
+	DER:     "der",
+	PEM:     "pem",
+	OpenSSH: "openssh",
+	JWK:     "jwk",
+	Raw:     "raw",
+}
+
+// ImportPublicKey decodes a PKIX DER or PEM key, an OpenSSH authorized_keys line or a JWK.
+// Without a name, the SSH comment or the JWK kid is used.
+func ImportPublicKey(name string, data []byte) (*ParsedPublicKey, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")):
+		block, rest := pem.Decode(trimmed)
+		if block == nil || len(bytes.TrimSpace(rest)) > 0 {
+			return nil, errors.New("invalid PEM public key")
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected PEM type %q", block.Type)
+		}
+		return NewParsedPublicKey(name, block.Bytes)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return importJWK(name, trimmed)
+	case bytes.HasPrefix(trimmed, []byte("ssh-")) || bytes.HasPrefix(trimmed, []byte("ecdsa-sha2-")):
+		key, comment, _, _, err := ssh.ParseAuthorizedKey(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		cryptoKey, ok := key.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported OpenSSH key type %s", key.Type())
+		}
+		return NewParsedPublicKeyFromKey(nameOr(name, comment), cryptoKey.CryptoPublicKey())
+	}
+	return NewParsedPublicKey(name, data)
+}
+
+// ImportRawPublicKey decodes an uncompressed or compressed SEC 1 point for EC key algorithms
+// and the 32 bytes key for Ed25519 and X25519.
+func ImportRawPublicKey(name string, alg KeyAlgT, data []byte) (*ParsedPublicKey, error) {
+	switch alg {
+	case KeyAlg.ED25519:
+		if len(data) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(data))
+		}
+		return NewParsedPublicKeyFromKey(name, ed25519.PublicKey(bytes.Clone(data)))
+	case KeyAlg.X25519:
+		key, err := ecdh.X25519().NewPublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+		return NewParsedPublicKeyFromKey(name, key)
+	}
+	key, err := parseECPoint(alg, data)
+	if err != nil {
+		return nil, err
+	}
+	return NewParsedPublicKeyFromKey(name, key)
+}
+
+// Export encodes the key in format. DER and PEM use the canonical PKIX encoding, Raw the
+// uncompressed point of EC keys.
+func (p *ParsedPublicKey) Export(format string) ([]byte, error) {
+	switch format {
+	case PublicKeyFormats.DER:
+		return p.canonical, nil
+	case PublicKeyFormats.PEM:
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: p.canonical}), nil
+	case PublicKeyFormats.OpenSSH:
+		if p.keyAlg == KeyAlg.SECP224R1 || p.keyAlg == KeyAlg.SECP256K1 || p.keyAlg == KeyAlg.X25519 {
+			return nil, fmt.Errorf("OpenSSH doesn't support %s keys", p.keyAlg)
+		}
+		key, err := ssh.NewPublicKey(p.key)
+		if err != nil {
+			return nil, err
+		}
+		line := bytes.TrimSuffix(ssh.MarshalAuthorizedKey(key), []byte("\n"))
+		if len(p.name) > 0 {
+			line = append(append(line, ' '), p.name...)
+		}
+		return append(line, '\n'), nil
+	case PublicKeyFormats.JWK:
+		return exportJWK(p)
+	case PublicKeyFormats.Raw:
+		switch key := p.key.(type) {
+		case *ecdsa.PublicKey:
+			return elliptic.Marshal(key.Curve, key.X, key.Y), nil
+		case ed25519.PublicKey:
+			return key, nil
+		case *ecdh.PublicKey:
+			return key.Bytes(), nil
+		}
+		return nil, fmt.Errorf("%T keys have no raw encoding", p.key)
+	}
+	return nil, fmt.Errorf("unknown public key format %q", format)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// jwkCurves are the curve names of RFC 7518, RFC 8037 and RFC 8812.
+var jwkCurves = map[KeyAlgT]string{
+	KeyAlg.SECP256R1: "P-256",
+	KeyAlg.SECP384R1: "P-384",
+	KeyAlg.SECP521R1: "P-521",
+	KeyAlg.SECP256K1: "secp256k1",
+	KeyAlg.ED25519:   "Ed25519",
+	KeyAlg.X25519:    "X25519",
+}
+
+func importJWK(name string, data []byte) (*ParsedPublicKey, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, err
+	}
+	name = nameOr(name, jwk.Kid)
+	var alg KeyAlgT
+	for k, v := range jwkCurves {
+		if v == jwk.Crv {
+			alg = k
+		}
+	}
+	switch jwk.Kty {
+	case "EC", "OKP":
+		if alg == "" || (jwk.Kty == "EC") != (alg.Curve() != nil) {
+			return nil, fmt.Errorf("unsupported JWK curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Kty == "OKP" {
+			return ImportRawPublicKey(name, alg, x)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (alg.Curve().Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("JWK coordinates must be %d bytes", size)
+		}
+		return ImportRawPublicKey(name, alg, append(append([]byte{4}, x...), y...))
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid JWK RSA exponent")
+		}
+		return NewParsedPublicKeyFromKey(name, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	}
+	return nil, fmt.Errorf("unsupported JWK key type %q", jwk.Kty)
+}
+
+func exportJWK(p *ParsedPublicKey) ([]byte, error) {
+	jwk := jsonWebKey{Kid: p.name}
+	switch key := p.key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	default:
+		crv, ok := jwkCurves[p.keyAlg]
+		if !ok {
+			return nil, fmt.Errorf("JWK doesn't support %s keys", p.keyAlg)
+		}
+		jwk.Crv = crv
+		raw, err := p.Export(PublicKeyFormats.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if ecKey, ok := key.(*ecdsa.PublicKey); ok {
+			size := (ecKey.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.X = base64.RawURLEncoding.EncodeToString(raw[1 : 1+size])
+			jwk.Y = base64.RawURLEncoding.EncodeToString(raw[1+size:])
+		} else {
+			jwk.Kty = "OKP"
+			jwk.X = base64.RawURLEncoding.EncodeToString(raw)
+		}
+	}
+	return json.Marshal(jwk)
+}
+
+func nameOr(name, fallback string) string {
+	if len(name) > 0 {
+		return name
+	}
+	return strings.TrimSpace(fallback)
+}
@@ -0,0 +1,84 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/samber/lo"
+	"strings"
+	"testing"
+)
+
+func TestPublicKeyExportImport(t *testing.T) {
+	ecKey := lo.Must(ecdsa.GenerateKey(elliptic.P384(), rand.Reader))
+	k1Key := lo.Must(GenerateSecp256k1Signer())
+	edKey, _ := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	rsaKey := lo.Must(rsa.GenerateKey(rand.Reader, 2048))
+
+	for _, pub := range []crypto.PublicKey{&ecKey.PublicKey, k1Key.Public(), edKey, &rsaKey.PublicKey} {
+		key := lo.Must(NewParsedPublicKeyFromKey("alice", pub))
+		for _, format := range []string{PublicKeyFormats.DER, PublicKeyFormats.PEM, PublicKeyFormats.OpenSSH, PublicKeyFormats.JWK, PublicKeyFormats.Raw} {
+			data, err := key.Export(format)
+			if err != nil {
+				if format == PublicKeyFormats.OpenSSH && key.KeyAlg() == KeyAlg.SECP256K1 || format == PublicKeyFormats.Raw && key.KeyAlg() == "" {
+					continue
+				}
+				t.Fatal(format, key.KeyAlg(), err)
+			}
+			var imported *ParsedPublicKey
+			if format == PublicKeyFormats.Raw {
+				imported, err = ImportRawPublicKey("alice", key.KeyAlg(), data)
+			} else {
+				imported, err = ImportPublicKey("", data)
+			}
+			if err != nil {
+				t.Fatal(format, key.KeyAlg(), err)
+			}
+			if !imported.Equal(key) {
+				t.Fatal("invalid import", format, key.KeyAlg())
+			}
+			if format == PublicKeyFormats.OpenSSH || format == PublicKeyFormats.JWK {
+				if imported.GetName() != "alice" {
+					t.Fatal("name must be kept", format, imported.GetName())
+				}
+			}
+		}
+	}
+}
+
+func TestImportPublicKey(t *testing.T) {
+	// RFC 7517 A.1
+	jwk := `{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		"y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","use":"enc","kid":"1"}`
+	key := lo.Must(ImportPublicKey("", []byte(jwk)))
+	if key.KeyAlg() != KeyAlg.SECP256R1 || key.GetName() != "1" {
+		t.Fatal("invalid JWK import")
+	}
+	compressed := elliptic.MarshalCompressed(elliptic.P256(), key.PublicKey().(*ecdsa.PublicKey).X, key.PublicKey().(*ecdsa.PublicKey).Y)
+	if !lo.Must(ImportRawPublicKey("wallet", KeyAlg.SECP256R1, compressed)).Equal(key) {
+		t.Fatal("invalid raw import")
+	}
+
+	group := &AccessGroup{Name: "approvers", Quorum: 1, PublicKeys: []Publickey{key}}
+	lo.Must0(group.Validate())
+
+	for _, invalid := range []string{
+		`{"kty":"EC","crv":"P-224","x":"AA","y":"AA"}`,
+		`{"kty":"OKP","crv":"P-256","x":"AA"}`,
+		`{"kty":"oct","k":"AA"}`,
+		"-----BEGIN CERTIFICATE-----\nAA==\n-----END CERTIFICATE-----\n",
+		"ssh-dss AAAA",
+	} {
+		if _, err := ImportPublicKey("", []byte(invalid)); err == nil {
+			t.Fatal("expected error", invalid)
+		}
+	}
+	ecKey := lo.Must(ecdsa.GenerateKey(elliptic.P224(), rand.Reader))
+	if _, err := lo.Must(NewParsedPublicKeyFromKey("", &ecKey.PublicKey)).Export(PublicKeyFormats.JWK); err == nil ||
+		!strings.Contains(err.Error(), "SECP224R1") {
+		t.Fatal("expected unsupported curve error, got", err)
+	}
+}
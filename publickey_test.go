@@ -0,0 +1,73 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"github.com/donutnomad/blockchain-alg/xx509"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func compressedPKIX(alg KeyAlgT, pub *ecdsa.PublicKey) []byte {
+	params := lo.Must(asn1.Marshal(alg.OID()))
+	return xx509.MarshalPKIXPublicKeyRaw(elliptic.MarshalCompressed(alg.Curve(), pub.X, pub.Y), pkix.AlgorithmIdentifier{
+		Algorithm:  xx509.OidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	})
+}
+
+func TestParsedPublicKeyEquality(t *testing.T) {
+	for _, alg := range []KeyAlgT{KeyAlg.SECP256R1, KeyAlg.SECP384R1, KeyAlg.SECP256K1} {
+		priv := lo.Must(ecdsa.GenerateKey(alg.Curve(), rand.Reader))
+		uncompressed := lo.Must(NewParsedPublicKey("alice", lo.Must(marshalPKIXPublicKey(&priv.PublicKey))))
+		compressed := lo.Must(NewParsedPublicKey("alice", compressedPKIX(alg, &priv.PublicKey)))
+		if !uncompressed.Equal(compressed) || uncompressed.Fingerprint() != compressed.Fingerprint() {
+			t.Fatal("keys must be equal", alg)
+		}
+		if compressed.KeyAlg() != alg || len(compressed.GetEncoded()) >= len(compressed.Canonical()) {
+			t.Fatal("invalid parsed key", alg)
+		}
+
+		group := &AccessGroup{Name: "approvers", Quorum: 1, PublicKeys: []Publickey{NewPublicKeyImpl("alice", uncompressed.GetEncoded())}}
+		if group.Count([][]byte{compressed.GetEncoded()}) != 1 {
+			t.Fatal("compressed key must be counted", alg)
+		}
+		group.PublicKeys = append(group.PublicKeys, compressed)
+		if group.Validate() == nil {
+			t.Fatal("expected duplicate key error", alg)
+		}
+	}
+
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	point := elliptic.Marshal(elliptic.P256(), priv.X, priv.Y)
+	point[len(point)-1] ^= 1
+	if _, err := parseECPoint(KeyAlg.SECP256R1, point); err == nil {
+		t.Fatal("point is not on the curve")
+	}
+	if _, err := parseECPoint(KeyAlg.SECP256K1, point); err == nil {
+		t.Fatal("point is not on the curve")
+	}
+}
+
+func TestBundleCompressedSigner(t *testing.T) {
+	signer := lo.Must(GenerateSecp256k1Signer())
+	pub := signer.Public().(*ecdsa.PublicKey)
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "key")
+	token := lo.Must(approval.Authorize(signer, EcdsaSignAlg.SHA256withECDSA))
+	compressed := NewPrimusAuthorizationTokenEncode(approval.Serialize(), lo.Must(token.GetVerifySignatureBytes()),
+		EcdsaSignAlg.SHA256withECDSA, compressedPKIX(KeyAlg.SECP256K1, pub), false)
+
+	bundle := NewAuthorizationBundle(approval.Serialize())
+	lo.Must0(bundle.Add(token))
+	if err := bundle.Add(compressed); !errors.Is(err, ErrDuplicateSigner) {
+		t.Fatal("expected duplicate signer, got", err)
+	}
+	verified := lo.Must(VerifyAuthorizationToken(compressed.GetEncoding(), nil))
+	if verified.Key.KeyAlg() != KeyAlg.SECP256K1 || !verified.Key.Equal(bundle.Verified()[0].Key) {
+		t.Fatal("invalid signer")
+	}
+}
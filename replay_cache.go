@@ -0,0 +1,227 @@
+package primus
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayKey identifies one approval of one approval token by one signer.
+type ReplayKey [32]byte
+
+// NewReplayKey hashes the approval token bytes together with the signer's encoded public key,
+// in its canonical encoding so a re-encoded key doesn't make a new key.
+func NewReplayKey(approvalTokenBytes []byte, publicKey []byte) ReplayKey {
+	h := sha256.New()
+	h.Write(LEUint32(len(approvalTokenBytes)))
+	h.Write(approvalTokenBytes)
+	h.Write(canonicalPublicKey(publicKey))
+	var key ReplayKey
+	h.Sum(key[:0])
+	return key
+}
+
+func (k ReplayKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+type ReplayCache interface {
+	// Add stores key until expiresAt, a zero expiresAt never expires.
+	// It returns false if key is already stored and not expired at now.
+	Add(key ReplayKey, now, expiresAt time.Time) (bool, error)
+}
+
+type MemoryReplayCache struct {
+	mu      sync.Mutex
+	entries map[ReplayKey]time.Time
+}
+
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{entries: make(map[ReplayKey]time.Time)}
+}
+
+func (c *MemoryReplayCache) Add(key ReplayKey, now, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune(now)
+	if _, ok := c.entries[key]; ok {
+		return false, nil
+	}
+	c.entries[key] = expiresAt
+	return true, nil
+}
+
+func (c *MemoryReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *MemoryReplayCache) prune(now time.Time) int {
+	var count = 0
+	for key, expiresAt := range c.entries {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(c.entries, key)
+			count++
+		}
+	}
+	return count
+}
+
+// FileReplayCache is a ReplayCache persisted to a text file, one "<key> <unix expiry>" per line.
+// The file is re-read on every Add, several processes on one host may share it: Add holds the
+// lock file "<path>.lock" while reading and writing.
+type FileReplayCache struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileReplayCache(path string) (*FileReplayCache, error) {
+	c := &FileReplayCache{path: path}
+	if _, err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *FileReplayCache) Add(key ReplayKey, now, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	unlock, err := c.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	mem, err := c.load()
+	if err != nil {
+		return false, err
+	}
+	total := len(mem.entries)
+	pruned := mem.prune(now)
+	if _, ok := mem.entries[key]; ok {
+		return false, nil
+	}
+	mem.entries[key] = expiresAt
+	if pruned > 0 && pruned*2 >= total {
+		return true, c.rewrite(mem.entries)
+	}
+	return true, c.append(key, expiresAt)
+}
+
+// replayLockTimeout is how long Add waits for the lock file. A lock file older than that was left
+// by a crashed process and is removed.
+const replayLockTimeout = 10 * time.Second
+
+func (c *FileReplayCache) lock() (unlock func(), err error) {
+	name := c.path + ".lock"
+	deadline := time.Now().Add(replayLockTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(name) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > replayLockTimeout {
+			_ = os.Remove(name)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("replay cache %s is locked", c.path)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (c *FileReplayCache) load() (*MemoryReplayCache, error) {
+	mem := NewMemoryReplayCache()
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return mem, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+		key, expiresAt, err := parseReplayLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", c.path, line, err)
+		}
+		mem.entries[key] = expiresAt
+	}
+	return mem, scanner.Err()
+}
+
+func (c *FileReplayCache) append(key ReplayKey, expiresAt time.Time) error {
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(formatReplayLine(key, expiresAt)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c *FileReplayCache) rewrite(entries map[ReplayKey]time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for key, expiresAt := range entries {
+		_, _ = w.WriteString(formatReplayLine(key, expiresAt))
+	}
+	if err = w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+func formatReplayLine(key ReplayKey, expiresAt time.Time) string {
+	var expiry int64
+	if !expiresAt.IsZero() {
+		expiry = expiresAt.Unix()
+	}
+	return key.String() + " " + strconv.FormatInt(expiry, 10) + "\n"
+}
+
+func parseReplayLine(line string) (key ReplayKey, expiresAt time.Time, err error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return key, expiresAt, fmt.Errorf("invalid replay cache entry %q", line)
+	}
+	bs, err := hex.DecodeString(fields[0])
+	if err != nil || len(bs) != len(key) {
+		return key, expiresAt, fmt.Errorf("invalid replay cache key %q", fields[0])
+	}
+	copy(key[:], bs)
+	expiry, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return key, expiresAt, fmt.Errorf("invalid replay cache expiry %q", fields[1])
+	}
+	if expiry != 0 {
+		expiresAt = time.Unix(expiry, 0)
+	}
+	return key, expiresAt, nil
+}
@@ -0,0 +1,141 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"errors"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/cryptobyte"
+	asn1_ "golang.org/x/crypto/cryptobyte/asn1"
+	"io"
+	"math/big"
+)
+
+// DeterministicSigner is a crypto.Signer for ECDSA keys deriving nonces from the key and the
+// digest as described in RFC 6979, the same digest always produces the same signature. It
+// can be passed to any registered ECDSA algorithm in place of the private key.
+//
+// WARNING: the signature is computed with variable-time math/big and elliptic.Curve arithmetic,
+// timing can leak the private key. It is meant for test vectors and reproducible fixtures, don't
+// use it with production keys.
+type DeterministicSigner struct {
+	priv *ecdsa.PrivateKey
+}
+
+func NewDeterministicSigner(priv *ecdsa.PrivateKey) (*DeterministicSigner, error) {
+	if _, err := ecdsaPublicKey(&priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if priv.D == nil || priv.D.Sign() <= 0 || priv.D.Cmp(priv.Curve.Params().N) >= 0 {
+		return nil, errors.New("invalid ECDSA private key")
+	}
+	return &DeterministicSigner{priv: priv}, nil
+}
+
+func (s *DeterministicSigner) Public() crypto.PublicKey {
+	return &s.priv.PublicKey
+}
+
+// Sign ignores rand. opts must name the hash function digest was computed with, it's used for
+// the nonce derivation.
+func (s *DeterministicSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts == nil || opts.HashFunc() == 0 || !opts.HashFunc().Available() {
+		return nil, errors.New("deterministic ECDSA signatures require a hash function")
+	}
+	r, sig := signRFC6979(s.priv, opts.HashFunc(), digest)
+	var b cryptobyte.Builder
+	b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1BigInt(r)
+		b.AddASN1BigInt(sig)
+	})
+	return b.Bytes()
+}
+
+func signRFC6979(priv *ecdsa.PrivateKey, hash crypto.Hash, digest []byte) (r, s *big.Int) {
+	curve := priv.Curve
+	if isSecp256k1(curve) {
+		curve = secp256k1.S256()
+	}
+	n := curve.Params().N
+	e := bitsToInt(digest, n.BitLen())
+	nonces := newRFC6979Nonces(priv.D, n, hash, digest)
+	for {
+		k := nonces.next()
+		x, _ := curve.ScalarBaseMult(k.FillBytes(make([]byte, curveOrderSize(curve))))
+		r = new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+		// s = k^-1 * (e + r*d) mod n
+		s = new(big.Int).Mul(r, priv.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, n))
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s
+		}
+	}
+}
+
+// rfc6979Nonces generates the candidate nonces of RFC 6979 section 3.2.
+type rfc6979Nonces struct {
+	n    *big.Int
+	hash crypto.Hash
+	k, v []byte
+}
+
+func newRFC6979Nonces(x, n *big.Int, hash crypto.Hash, digest []byte) *rfc6979Nonces {
+	size := (n.BitLen() + 7) / 8
+	h1 := bitsToInt(digest, n.BitLen())
+	if h1.Cmp(n) >= 0 {
+		h1.Sub(h1, n)
+	}
+	key := append(x.FillBytes(make([]byte, size)), h1.FillBytes(make([]byte, size))...)
+
+	g := &rfc6979Nonces{n: n, hash: hash}
+	g.v = make([]byte, hash.Size())
+	g.k = make([]byte, hash.Size())
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+	for _, sep := range []byte{0x00, 0x01} {
+		g.k = g.mac(g.k, g.v, []byte{sep}, key)
+		g.v = g.mac(g.k, g.v)
+	}
+	return g
+}
+
+func (g *rfc6979Nonces) next() *big.Int {
+	for {
+		var t []byte
+		for len(t)*8 < g.n.BitLen() {
+			g.v = g.mac(g.k, g.v)
+			t = append(t, g.v...)
+		}
+		k := bitsToInt(t, g.n.BitLen())
+		// the state is updated after every candidate, so a rejected r or s yields a new nonce
+		g.k = g.mac(g.k, g.v, []byte{0x00})
+		g.v = g.mac(g.k, g.v)
+		if k.Sign() > 0 && k.Cmp(g.n) < 0 {
+			return k
+		}
+	}
+}
+
+func (g *rfc6979Nonces) mac(key []byte, data ...[]byte) []byte {
+	m := hmac.New(g.hash.New, key)
+	for _, v := range data {
+		m.Write(v)
+	}
+	return m.Sum(nil)
+}
+
+// bitsToInt interprets the leftmost qlen bits of bs as an integer.
+func bitsToInt(bs []byte, qlen int) *big.Int {
+	ret := new(big.Int).SetBytes(bs)
+	if blen := len(bs) * 8; blen > qlen {
+		ret.Rsh(ret, uint(blen-qlen))
+	}
+	return ret
+}
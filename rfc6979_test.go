@@ -0,0 +1,65 @@
+package primus
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/samber/lo"
+	"math/big"
+	"testing"
+)
+
+func testRFC6979Key(curve elliptic.Curve) *ecdsa.PrivateKey {
+	// private key of RFC 6979 A.2.5
+	d, _ := new(big.Int).SetString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", 16)
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+	return priv
+}
+
+func TestDeterministicSignerVectors(t *testing.T) {
+	signer := lo.Must(NewDeterministicSigner(testRFC6979Key(elliptic.P256())))
+	for _, v := range []struct {
+		alg  SignAlgT
+		r, s string
+	}{
+		{EcdsaSignAlg.SHA256withECDSA,
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+		{EcdsaSignAlg.SHA384withECDSA,
+			"0EAFEA039B20E9B42309FB1D89E213057CBF973DC0CFC8F129EDDDC800EF7719",
+			"4861F0491E6998B9455193E34E7B0D284DDD7149A74B95B9261F13ABDE940954"},
+	} {
+		sig := lo.Must(FindSignatureAlgorithm(v.alg).SignMessage(signer, []byte("sample")))
+		raw := lo.Must(ASN1ToRawSignature(KeyAlg.SECP256R1, sig, nil))
+		if !bytes.Equal(raw, append(mustDecode(v.r), mustDecode(v.s)...)) {
+			t.Fatalf("%s: unexpected signature %x", v.alg, raw)
+		}
+	}
+
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("content to be sign"), "gt_ec_08")
+	first := lo.Must(approval.Authorize(signer, EcdsaSignAlg.SHA512withECDSA))
+	second := lo.Must(approval.Authorize(signer, EcdsaSignAlg.SHA512withECDSA))
+	if !bytes.Equal(first.GetEncoding(), second.GetEncoding()) {
+		t.Fatal("signatures must be reproducible")
+	}
+	lo.Must(VerifyAuthorizationToken(first.GetEncoding(), nil))
+}
+
+func TestDeterministicSignerSecp256k1(t *testing.T) {
+	priv := testRFC6979Key(secp256k1.S256())
+	signer := lo.Must(NewDeterministicSigner(priv))
+	digest := sha256.Sum256([]byte("sample"))
+	sig := lo.Must(signer.Sign(nil, digest[:], crypto.SHA256))
+
+	// decred derives its nonces with RFC 6979 too, but normalizes S
+	expected := lo.Must(ASN1ToRawSignature(KeyAlg.SECP256K1, signSecp256k1(toSecp256k1PrivateKey(priv), digest[:]), nil))
+	actual := lo.Must(ASN1ToRawSignature(KeyAlg.SECP256K1, sig, &SignatureEncodingOptions{LowS: true}))
+	if !bytes.Equal(expected, actual) {
+		t.Fatalf("unexpected signature %x", actual)
+	}
+}
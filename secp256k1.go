@@ -0,0 +1,122 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp_ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/donutnomad/blockchain-alg/xx509"
+	"io"
+)
+
+// crypto/ecdsa only implements the NIST curves, keys on secp256k1 are handled by the
+// implementation of decred instead.
+
+func isSecp256k1(curve elliptic.Curve) bool {
+	return curve == secp256k1.S256() || curve.Params().Name == secp256k1.S256().Params().Name
+}
+
+func toSecp256k1PublicKey(pub *ecdsa.PublicKey) (*secp256k1.PublicKey, error) {
+	var x, y secp256k1.FieldVal
+	if pub.X.Sign() < 0 || pub.Y.Sign() < 0 || x.SetByteSlice(pub.X.Bytes()) || y.SetByteSlice(pub.Y.Bytes()) {
+		return nil, errors.New("invalid secp256k1 public key")
+	}
+	key := secp256k1.NewPublicKey(&x, &y)
+	if !key.IsOnCurve() {
+		return nil, errors.New("secp256k1 public key is not on the curve")
+	}
+	return key, nil
+}
+
+func toSecp256k1PrivateKey(priv *ecdsa.PrivateKey) *secp256k1.PrivateKey {
+	return secp256k1.PrivKeyFromBytes(priv.D.FillBytes(make([]byte, 32)))
+}
+
+// truncateSecp256k1Digest keeps the leftmost 256 bits of digest, as ECDSA does for digests
+// longer than the group order.
+func truncateSecp256k1Digest(digest []byte) []byte {
+	if len(digest) > 32 {
+		return digest[:32]
+	}
+	return digest
+}
+
+func signSecp256k1(priv *secp256k1.PrivateKey, digest []byte) []byte {
+	return secp_ecdsa.Sign(priv, truncateSecp256k1Digest(digest)).Serialize()
+}
+
+func verifySecp256k1(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	key, err := toSecp256k1PublicKey(pub)
+	if err != nil {
+		return false
+	}
+	signature, err := secp_ecdsa.ParseDERSignature(sig)
+	if err != nil {
+		return false
+	}
+	return signature.Verify(truncateSecp256k1Digest(digest), key)
+}
+
+// Secp256k1Signer is a crypto.Signer for secp256k1 keys producing ASN1 signatures with
+// RFC 6979 nonces and low S values.
+type Secp256k1Signer struct {
+	key *secp256k1.PrivateKey
+}
+
+// NewSecp256k1Signer creates a signer from a 32 bytes big endian private scalar.
+func NewSecp256k1Signer(privateKey []byte) (*Secp256k1Signer, error) {
+	if len(privateKey) != 32 {
+		return nil, errors.New("secp256k1 private key must be 32 bytes")
+	}
+	key := secp256k1.PrivKeyFromBytes(privateKey)
+	if key.Key.IsZero() {
+		return nil, errors.New("invalid secp256k1 private key")
+	}
+	return &Secp256k1Signer{key: key}, nil
+}
+
+func GenerateSecp256k1Signer() (*Secp256k1Signer, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Secp256k1Signer{key: key}, nil
+}
+
+func (s *Secp256k1Signer) Public() crypto.PublicKey {
+	return s.key.PubKey().ToECDSA()
+}
+
+func (s *Secp256k1Signer) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return signSecp256k1(s.key, digest), nil
+}
+
+// Bytes returns the 32 bytes private scalar.
+func (s *Secp256k1Signer) Bytes() []byte {
+	return s.key.Serialize()
+}
+
+// marshalPKIXPublicKey is x509.MarshalPKIXPublicKey with support for secp256k1 keys.
+func marshalPKIXPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok || !isSecp256k1(key.Curve) {
+		return x509.MarshalPKIXPublicKey(pub)
+	}
+	k1, err := toSecp256k1PublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(xx509.OidNameCurveSecp256k1)
+	if err != nil {
+		return nil, err
+	}
+	return xx509.MarshalPKIXPublicKeyRaw(k1.SerializeUncompressed(), pkix.AlgorithmIdentifier{
+		Algorithm:  xx509.OidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}), nil
+}
@@ -0,0 +1,60 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"github.com/donutnomad/blockchain-alg/xx509"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestSecp256k1AuthorizationToken(t *testing.T) {
+	signer := lo.Must(GenerateSecp256k1Signer())
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "k1")
+
+	for _, alg := range []SignAlgT{EcdsaSignAlg.SHA256withECDSA, EcdsaSignAlg.SHA512withECDSA} {
+		token := lo.Must(approval.Authorize(signer, alg))
+		verified, err := VerifyAuthorizationToken(token.GetEncoding(), nil)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		if !signer.Public().(*ecdsa.PublicKey).Equal(verified.PublicKey) {
+			t.Fatal("invalid public key")
+		}
+	}
+
+	// *ecdsa.PrivateKey on secp256k1 is signed by the same backend
+	priv := lo.Must(ecdsa.GenerateKey(signer.Public().(*ecdsa.PublicKey).Curve, rand.Reader))
+	token := lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+	if _, err := VerifyAuthorizationToken(token.GetEncoding(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecp256k1Interop(t *testing.T) {
+	// public key of TestCheck2
+	pub := lo.Must(xx509.ParsePKIXPublicKey(lo.Must(base64.StdEncoding.DecodeString("MFYwEAYHKoZIzj0CAQYFK4EEAAoDQgAEuiW6iumaFdLz8hZgvkvltYT9zROMRrfL46RqY4CmHP/oSP0NS8kZuYZjeXYzAYtn0k1v8/8KcojhfI6q4AT7ng=="))))
+	if _, err := ecdsaPublicKey(pub); err != nil {
+		t.Fatal(err)
+	}
+	if bs := lo.Must(marshalPKIXPublicKey(pub)); base64.StdEncoding.EncodeToString(bs) != "MFYwEAYHKoZIzj0CAQYFK4EEAAoDQgAEuiW6iumaFdLz8hZgvkvltYT9zROMRrfL46RqY4CmHP/oSP0NS8kZuYZjeXYzAYtn0k1v8/8KcojhfI6q4AT7ng==" {
+		t.Fatal("invalid PKIX encoding")
+	}
+
+	// signatures of the generic elliptic implementation verify with the secp256k1 backend and vice versa
+	signer := lo.Must(GenerateSecp256k1Signer())
+	key := signer.key.ToECDSA()
+	for _, digest := range [][]byte{lo.ToPtr(sha256.Sum256([]byte("x")))[:], lo.ToPtr(sha512.Sum512([]byte("x")))[:]} {
+		sig := lo.Must(signer.Sign(rand.Reader, digest, nil))
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest, sig) {
+			t.Fatal("generic verify failed")
+		}
+		sig = lo.Must(ecdsa.SignASN1(rand.Reader, key, digest))
+		if !verifySecp256k1(&key.PublicKey, digest, sig) {
+			t.Fatal("secp256k1 verify failed")
+		}
+	}
+}
@@ -1,81 +1,220 @@
 package primus
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"crypto/subtle"
+	"encoding/asn1"
+	"fmt"
+	_ "golang.org/x/crypto/sha3"
 	"hash"
+	"io"
 )
 
-type EcdsaSignAlgT string
+// SignAlgT names a SignatureAlgorithm, using the Java algorithm names.
+type SignAlgT string
 
-func (t EcdsaSignAlgT) String() string {
+func (t SignAlgT) String() string {
 	return string(t)
 }
 
+// EcdsaSignAlgT is the former name of SignAlgT.
+type EcdsaSignAlgT = SignAlgT
+
 var EcdsaSignAlg = struct {
-	SHA1withECDSA   EcdsaSignAlgT
-	SHA224withECDSA EcdsaSignAlgT
-	SHA256withECDSA EcdsaSignAlgT
-	SHA384withECDSA EcdsaSignAlgT
-	SHA512withECDSA EcdsaSignAlgT
+	SHA1withECDSA     EcdsaSignAlgT
+	SHA224withECDSA   EcdsaSignAlgT
+	SHA256withECDSA   EcdsaSignAlgT
+	SHA384withECDSA   EcdsaSignAlgT
+	SHA512withECDSA   EcdsaSignAlgT
+	SHA3_256withECDSA EcdsaSignAlgT
+	SHA3_384withECDSA EcdsaSignAlgT
+	SHA3_512withECDSA EcdsaSignAlgT
 }{
-	SHA1withECDSA:   "SHA1withECDSA",
-	SHA224withECDSA: "SHA224withECDSA",
-	SHA256withECDSA: "SHA256withECDSA",
-	SHA384withECDSA: "SHA384withECDSA",
-	SHA512withECDSA: "SHA512withECDSA",
+	SHA1withECDSA:     "SHA1withECDSA",
+	SHA224withECDSA:   "SHA224withECDSA",
+	SHA256withECDSA:   "SHA256withECDSA",
+	SHA384withECDSA:   "SHA384withECDSA",
+	SHA512withECDSA:   "SHA512withECDSA",
+	SHA3_256withECDSA: "SHA3-256withECDSA",
+	SHA3_384withECDSA: "SHA3-384withECDSA",
+	SHA3_512withECDSA: "SHA3-512withECDSA",
 }
 
-var ecdsaObjects = []*ECDSAObject{
-	NewECDSAObject(EcdsaSignAlg.SHA1withECDSA, []byte{42, 134, 72, 206, 61, 4, 1}, sha1.New),
-	NewECDSAObject(EcdsaSignAlg.SHA224withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 1}, sha256.New224),
-	NewECDSAObject(EcdsaSignAlg.SHA256withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 2}, sha256.New),
-	NewECDSAObject(EcdsaSignAlg.SHA384withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 3}, sha512.New384),
-	NewECDSAObject(EcdsaSignAlg.SHA512withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 4}, sha512.New),
+func init() {
+	mustRegisterSignatureAlgorithm(
+		newECDSAObject(EcdsaSignAlg.SHA1withECDSA, []byte{42, 134, 72, 206, 61, 4, 1}, crypto.SHA1),
+		newECDSAObject(EcdsaSignAlg.SHA224withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 1}, crypto.SHA224),
+		newECDSAObject(EcdsaSignAlg.SHA256withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 2}, crypto.SHA256),
+		newECDSAObject(EcdsaSignAlg.SHA384withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 3}, crypto.SHA384),
+		newECDSAObject(EcdsaSignAlg.SHA512withECDSA, []byte{42, 134, 72, 206, 61, 4, 3, 4}, crypto.SHA512),
+		newECDSAObject(EcdsaSignAlg.SHA3_256withECDSA, []byte{96, 134, 72, 1, 101, 3, 4, 3, 10}, crypto.SHA3_256),
+		newECDSAObject(EcdsaSignAlg.SHA3_384withECDSA, []byte{96, 134, 72, 1, 101, 3, 4, 3, 11}, crypto.SHA3_384),
+		newECDSAObject(EcdsaSignAlg.SHA3_512withECDSA, []byte{96, 134, 72, 1, 101, 3, 4, 3, 12}, crypto.SHA3_512),
+	)
 }
 
 type ECDSAObject struct {
 	name   EcdsaSignAlgT
 	oid    []byte
+	hash   crypto.Hash
 	hasher func() hash.Hash
 }
 
+func (o *ECDSAObject) Name() SignAlgT {
+	return o.name
+}
+
+func (o *ECDSAObject) OID() asn1.ObjectIdentifier {
+	oid, _ := parseOid(o.oid)
+	return oid
+}
+
+// Parameters returns NULL, as written by the HSM.
+func (o *ECDSAObject) Parameters() []byte {
+	return asn1.NullBytes
+}
+
+// CheckKey reports whether pub is an ECDSA key whose curve is not stronger than the digest of o.
+func (o *ECDSAObject) CheckKey(pub crypto.PublicKey) error {
+	key, err := ecdsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	curveBits := min(key.Curve.Params().BitSize, 512)
+	if o.hasher().Size()*8 < curveBits {
+		return fmt.Errorf("%s is too weak for %s", o.name, key.Curve.Params().Name)
+	}
+	return nil
+}
+
+// SignMessage hashes input and signs the digest with signer, the result is ASN1 encoded.
+func (o *ECDSAObject) SignMessage(signer crypto.Signer, input []byte) ([]byte, error) {
+	return o.SignDigest(signer, o.sum(input))
+}
+
+func (o *ECDSAObject) VerifyMessage(pub crypto.PublicKey, input, sig []byte) error {
+	return o.VerifyDigest(pub, o.sum(input), sig)
+}
+
+func (o *ECDSAObject) Digest(r io.Reader) ([]byte, error) {
+	return hashReader(o.hasher(), r)
+}
+
+// SignDigest signs a digest returned by Digest, the result is ASN1 encoded.
+func (o *ECDSAObject) SignDigest(signer crypto.Signer, digest []byte) ([]byte, error) {
+	if err := checkDigestSize(o.hasher(), digest); err != nil {
+		return nil, err
+	}
+	if priv, ok := signer.(*ecdsa.PrivateKey); ok {
+		return o.signDigest(priv, digest)
+	}
+	if o.hash == 0 {
+		return nil, fmt.Errorf("%s: signer needs a standard hash function", o.name)
+	}
+	return signer.Sign(rand.Reader, digest, o.hash)
+}
+
+func (o *ECDSAObject) VerifyDigest(pub crypto.PublicKey, digest, sig []byte) error {
+	key, err := ecdsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if checkDigestSize(o.hasher(), digest) != nil || !o.verifyDigest(key, digest, sig) {
+		return SignatureError{Algorithm: o.name}
+	}
+	return nil
+}
+
+// SignReader hashes the message read from r and signs the digest.
+func (o *ECDSAObject) SignReader(signer crypto.Signer, r io.Reader) ([]byte, error) {
+	return SignReader(o, signer, r)
+}
+
+func (o *ECDSAObject) VerifyReader(pub crypto.PublicKey, r io.Reader, sig []byte) error {
+	return VerifyReader(o, pub, r, sig)
+}
+
 func (o *ECDSAObject) Sign(priv *ecdsa.PrivateKey, input []byte) ([]byte, error) {
-	var tmp [512]byte
-	var h = o.hasher()
-	h.Write(input)
-	return ecdsa.SignASN1(rand.Reader, priv, h.Sum(tmp[:0]))
+	return o.signDigest(priv, o.sum(input))
 }
 
 func (o *ECDSAObject) Verify(pub *ecdsa.PublicKey, input, sig []byte) bool {
-	var tmp [512]byte
+	return o.verifyDigest(pub, o.sum(input), sig)
+}
+
+func (o *ECDSAObject) sum(input []byte) []byte {
 	var h = o.hasher()
 	h.Write(input)
-	return ecdsa.VerifyASN1(pub, h.Sum(tmp[:0]), sig)
+	return h.Sum(nil)
+}
+
+func (o *ECDSAObject) signDigest(priv *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	if isSecp256k1(priv.Curve) {
+		return signSecp256k1(toSecp256k1PrivateKey(priv), digest), nil
+	}
+	return ecdsa.SignASN1(rand.Reader, priv, digest)
+}
+
+func (o *ECDSAObject) verifyDigest(pub *ecdsa.PublicKey, digest, sig []byte) bool {
+	if isSecp256k1(pub.Curve) {
+		return verifySecp256k1(pub, digest, sig)
+	}
+	return ecdsa.VerifyASN1(pub, digest, sig)
 }
 
 func NewECDSAObject(name EcdsaSignAlgT, oid []byte, hasher func() hash.Hash) *ECDSAObject {
-	return &ECDSAObject{name: name, oid: oid, hasher: hasher}
+	return &ECDSAObject{name: name, oid: oid, hash: findHash(hasher), hasher: hasher}
+}
+
+// findHash returns the crypto.Hash implemented by hasher, 0 if it is none of them.
+func findHash(hasher func() hash.Hash) crypto.Hash {
+	empty := hasher().Sum(nil)
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512,
+		crypto.SHA512_224, crypto.SHA512_256, crypto.SHA3_224, crypto.SHA3_256, crypto.SHA3_384, crypto.SHA3_512} {
+		if h.Available() && bytes.Equal(h.New().Sum(nil), empty) {
+			return h
+		}
+	}
+	return 0
+}
+
+func newECDSAObject(name EcdsaSignAlgT, oid []byte, hash crypto.Hash) *ECDSAObject {
+	return &ECDSAObject{name: name, oid: oid, hash: hash, hasher: hash.New}
 }
 
 func finEcdsaNameByOid(oid []byte) EcdsaSignAlgT {
-	for _, v := range ecdsaObjects {
-		if subtle.ConstantTimeCompare(v.oid, oid) != 0 {
-			return v.name
+	for _, v := range SignatureAlgorithms() {
+		if o, ok := v.(*ECDSAObject); ok && subtle.ConstantTimeCompare(o.oid, oid) != 0 {
+			return o.name
 		}
 	}
 	return ""
 }
 
+// FindEcdsaByName returns the registered ECDSA algorithm called name.
 func FindEcdsaByName(name EcdsaSignAlgT) *ECDSAObject {
-	for _, v := range ecdsaObjects {
-		if v.name == name {
-			return v
-		}
+	obj, _ := FindSignatureAlgorithm(name).(*ECDSAObject)
+	return obj
+}
+
+func ecdsaPublicKey(pub crypto.PublicKey) (*ecdsa.PublicKey, error) {
+	key, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, UnsupportedKeyError{Reason: fmt.Sprintf("expected an ECDSA key, got %T", pub)}
 	}
-	return nil
+	switch key.Curve {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return key, nil
+	}
+	if isSecp256k1(key.Curve) {
+		return key, nil
+	}
+	return nil, UnsupportedKeyError{Reason: "unsupported elliptic curve " + key.Curve.Params().Name}
 }
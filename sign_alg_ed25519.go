@@ -0,0 +1,66 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/asn1"
+	"fmt"
+)
+
+var EddsaSignAlg = struct {
+	Ed25519 SignAlgT
+}{
+	Ed25519: "Ed25519",
+}
+
+func init() {
+	mustRegisterSignatureAlgorithm(new(Ed25519Object))
+}
+
+// Ed25519Object is pure Ed25519 (RFC 8410), the message is signed without prehashing.
+type Ed25519Object struct{}
+
+func (o *Ed25519Object) Name() SignAlgT {
+	return EddsaSignAlg.Ed25519
+}
+
+func (o *Ed25519Object) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 3, 101, 112}
+}
+
+// Parameters returns nil, RFC 8410 requires them to be absent.
+func (o *Ed25519Object) Parameters() []byte {
+	return nil
+}
+
+func (o *Ed25519Object) CheckKey(pub crypto.PublicKey) error {
+	_, err := ed25519PublicKey(pub)
+	return err
+}
+
+func (o *Ed25519Object) SignMessage(signer crypto.Signer, input []byte) ([]byte, error) {
+	return signer.Sign(rand.Reader, input, crypto.Hash(0))
+}
+
+func (o *Ed25519Object) VerifyMessage(pub crypto.PublicKey, input, sig []byte) error {
+	key, err := ed25519PublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, input, sig) {
+		return SignatureError{Algorithm: o.Name()}
+	}
+	return nil
+}
+
+func ed25519PublicKey(pub crypto.PublicKey) (ed25519.PublicKey, error) {
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, UnsupportedKeyError{Reason: fmt.Sprintf("expected an Ed25519 key, got %T", pub)}
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, UnsupportedKeyError{Reason: "invalid Ed25519 key size"}
+	}
+	return key, nil
+}
@@ -0,0 +1,64 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"github.com/samber/lo"
+	"testing"
+)
+
+func TestEd25519AuthorizationToken(t *testing.T) {
+	pub, priv := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.MODIFY, []byte("new access"), "ed_key")
+
+	token := lo.Must(approval.Authorize(priv, EddsaSignAlg.Ed25519))
+	der := lo.Must(token.GetDerSignatureBytes())
+	// SEQUENCE { SEQUENCE { OID 1.3.101.112 }, BIT STRING }
+	if !bytes.Equal(der[:9], []byte{0x30, 0x4a, 0x30, 0x05, 0x06, 0x03, 0x2b, 0x65, 0x70}) {
+		t.Fatal("invalid encoding", der)
+	}
+	if ExtractSignAlgorithm(der) != EddsaSignAlg.Ed25519 {
+		t.Fatal("invalid algorithm")
+	}
+	if !bytes.Equal(lo.Must(token.GetVerifySignatureBytes()), ed25519.Sign(priv, approval.Serialize())) {
+		t.Fatal("signature must be over the approval token bytes")
+	}
+
+	verified, err := VerifyAuthorizationToken(token.GetEncoding(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(verified.PublicKey) || verified.Algorithm != EddsaSignAlg.Ed25519 || verified.ApprovalToken.Operation != ApprovalTokenOp.MODIFY {
+		t.Fatal("invalid result")
+	}
+
+	// round trip through the decoder of PrimusSignature
+	ps := new(PrimusSignature)
+	ps.Deserialize(der)
+	if ps.signAlgorithm != EddsaSignAlg.Ed25519 || !bytes.Equal(ps.getEncodingWithSignAlgorithm(), der) {
+		t.Fatal("invalid PrimusSignature")
+	}
+
+	ecKey := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	if _, err = approval.Authorize(ecKey, EddsaSignAlg.Ed25519); err == nil {
+		t.Fatal("ECDSA key must be rejected")
+	}
+	wrongKey := NewPrimusAuthorizationTokenEncode(approval.Serialize(), lo.Must(token.GetVerifySignatureBytes()),
+		EddsaSignAlg.Ed25519, lo.Must(x509.MarshalPKIXPublicKey(&ecKey.PublicKey)), false)
+	var keyErr UnsupportedKeyError
+	if _, err = VerifyAuthorizationToken(wrongKey.GetEncoding(), nil); !errors.As(err, &keyErr) {
+		t.Fatal("expected UnsupportedKeyError, got", err)
+	}
+	other, _ := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	forged := NewPrimusAuthorizationTokenEncode(approval.Serialize(), lo.Must(token.GetVerifySignatureBytes()),
+		EddsaSignAlg.Ed25519, lo.Must(x509.MarshalPKIXPublicKey(other)), false)
+	var sigErr SignatureError
+	if _, err = VerifyAuthorizationToken(forged.GetEncoding(), nil); !errors.As(err, &sigErr) {
+		t.Fatal("expected SignatureError, got", err)
+	}
+}
@@ -0,0 +1,262 @@
+package primus
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"fmt"
+	"golang.org/x/crypto/cryptobyte"
+	asn1_ "golang.org/x/crypto/cryptobyte/asn1"
+	"io"
+)
+
+var RsaSignAlg = struct {
+	SHA256withRSA    SignAlgT
+	SHA384withRSA    SignAlgT
+	SHA512withRSA    SignAlgT
+	SHA256withRSAPSS SignAlgT
+	SHA384withRSAPSS SignAlgT
+	SHA512withRSAPSS SignAlgT
+}{
+	SHA256withRSA:    "SHA256withRSA",
+	SHA384withRSA:    "SHA384withRSA",
+	SHA512withRSA:    "SHA512withRSA",
+	SHA256withRSAPSS: "SHA256withRSA/PSS",
+	SHA384withRSAPSS: "SHA384withRSA/PSS",
+	SHA512withRSAPSS: "SHA512withRSA/PSS",
+}
+
+var (
+	oidSignatureRSAPSS = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidMGF1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidHashes          = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+)
+
+// minRSAKeyBits is the smallest modulus accepted for new signatures.
+const minRSAKeyBits = 2048
+
+func init() {
+	mustRegisterSignatureAlgorithm(
+		newRSAObject(RsaSignAlg.SHA256withRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, crypto.SHA256, false),
+		newRSAObject(RsaSignAlg.SHA384withRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, crypto.SHA384, false),
+		newRSAObject(RsaSignAlg.SHA512withRSA, asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, crypto.SHA512, false),
+		newRSAObject(RsaSignAlg.SHA256withRSAPSS, oidSignatureRSAPSS, crypto.SHA256, true),
+		newRSAObject(RsaSignAlg.SHA384withRSAPSS, oidSignatureRSAPSS, crypto.SHA384, true),
+		newRSAObject(RsaSignAlg.SHA512withRSAPSS, oidSignatureRSAPSS, crypto.SHA512, true),
+	)
+}
+
+// RSAObject is RSASSA-PKCS1-v1_5 or RSASSA-PSS. PSS uses MGF1 with the message digest and a
+// salt as long as the digest, as encoded in its parameters.
+type RSAObject struct {
+	name   SignAlgT
+	oid    asn1.ObjectIdentifier
+	hash   crypto.Hash
+	pss    bool
+	params []byte
+}
+
+func newRSAObject(name SignAlgT, oid asn1.ObjectIdentifier, hash crypto.Hash, pss bool) *RSAObject {
+	o := &RSAObject{name: name, oid: oid, hash: hash, pss: pss, params: asn1.NullBytes}
+	if pss {
+		o.params = encodePSSParameters(hash)
+	}
+	return o
+}
+
+func (o *RSAObject) Name() SignAlgT {
+	return o.name
+}
+
+func (o *RSAObject) OID() asn1.ObjectIdentifier {
+	return o.oid
+}
+
+func (o *RSAObject) Parameters() []byte {
+	return o.params
+}
+
+// MatchParameters accepts PSS parameters with or without NULL hash parameters.
+func (o *RSAObject) MatchParameters(params []byte) bool {
+	if !o.pss {
+		return isAbsentOrNull(params)
+	}
+	hash, mgfHash, saltLength, ok := parsePSSParameters(params)
+	return ok && hash == o.hash && mgfHash == o.hash && saltLength == o.hash.Size()
+}
+
+func (o *RSAObject) CheckKey(pub crypto.PublicKey) error {
+	key, err := rsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if key.N.BitLen() < minRSAKeyBits {
+		return UnsupportedKeyError{Reason: fmt.Sprintf("RSA key of %d bits is too small", key.N.BitLen())}
+	}
+	return nil
+}
+
+func (o *RSAObject) signerOpts() crypto.SignerOpts {
+	if o.pss {
+		return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: o.hash}
+	}
+	return o.hash
+}
+
+func (o *RSAObject) SignMessage(signer crypto.Signer, input []byte) ([]byte, error) {
+	return o.SignDigest(signer, o.sum(input))
+}
+
+func (o *RSAObject) VerifyMessage(pub crypto.PublicKey, input, sig []byte) error {
+	return o.VerifyDigest(pub, o.sum(input), sig)
+}
+
+func (o *RSAObject) Digest(r io.Reader) ([]byte, error) {
+	return hashReader(o.hash.New(), r)
+}
+
+func (o *RSAObject) SignDigest(signer crypto.Signer, digest []byte) ([]byte, error) {
+	if err := checkDigestSize(o.hash.New(), digest); err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand.Reader, digest, o.signerOpts())
+}
+
+func (o *RSAObject) VerifyDigest(pub crypto.PublicKey, digest, sig []byte) error {
+	key, err := rsaPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if o.pss {
+		err = rsa.VerifyPSS(key, o.hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	} else {
+		err = rsa.VerifyPKCS1v15(key, o.hash, digest, sig)
+	}
+	if err != nil {
+		return SignatureError{Algorithm: o.name}
+	}
+	return nil
+}
+
+func (o *RSAObject) SignReader(signer crypto.Signer, r io.Reader) ([]byte, error) {
+	return SignReader(o, signer, r)
+}
+
+func (o *RSAObject) VerifyReader(pub crypto.PublicKey, r io.Reader, sig []byte) error {
+	return VerifyReader(o, pub, r, sig)
+}
+
+func (o *RSAObject) sum(input []byte) []byte {
+	h := o.hash.New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+func rsaPublicKey(pub crypto.PublicKey) (*rsa.PublicKey, error) {
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, UnsupportedKeyError{Reason: fmt.Sprintf("expected an RSA key, got %T", pub)}
+	}
+	return key, nil
+}
+
+// encodePSSParameters encodes RSASSA-PSS-params (RFC 4055) the way crypto/x509 does:
+//
+//	SEQUENCE {
+//	  [0] AlgorithmIdentifier { hash, NULL }
+//	  [1] AlgorithmIdentifier { id-mgf1, AlgorithmIdentifier { hash, NULL } }
+//	  [2] INTEGER saltLength
+//	}
+func encodePSSParameters(hash crypto.Hash) []byte {
+	var b cryptobyte.Builder
+	addHashAlgorithm := func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+			b.AddASN1ObjectIdentifier(oidHashes[hash])
+			b.AddASN1NULL()
+		})
+	}
+	b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1_.Tag(0).Constructed().ContextSpecific(), addHashAlgorithm)
+		b.AddASN1(asn1_.Tag(1).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+				b.AddASN1ObjectIdentifier(oidMGF1)
+				addHashAlgorithm(b)
+			})
+		})
+		b.AddASN1(asn1_.Tag(2).Constructed().ContextSpecific(), func(b *cryptobyte.Builder) {
+			b.AddASN1Int64(int64(hash.Size()))
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func parsePSSParameters(params []byte) (hash, mgfHash crypto.Hash, saltLength int, ok bool) {
+	var input = cryptobyte.String(params)
+	var seq cryptobyte.String
+	if !input.ReadASN1(&seq, asn1_.SEQUENCE) || !input.Empty() {
+		return 0, 0, 0, false
+	}
+	// defaults of RFC 4055 are SHA-1 and a salt of 20 bytes
+	hash, mgfHash, saltLength = crypto.SHA1, crypto.SHA1, 20
+	var field cryptobyte.String
+	var present bool
+	if !seq.ReadOptionalASN1(&field, &present, asn1_.Tag(0).Constructed().ContextSpecific()) {
+		return 0, 0, 0, false
+	}
+	if present {
+		if hash, ok = readHashAlgorithm(&field); !ok {
+			return 0, 0, 0, false
+		}
+	}
+	if !seq.ReadOptionalASN1(&field, &present, asn1_.Tag(1).Constructed().ContextSpecific()) {
+		return 0, 0, 0, false
+	}
+	if present {
+		var mgf cryptobyte.String
+		var mgfOid asn1.ObjectIdentifier
+		if !field.ReadASN1(&mgf, asn1_.SEQUENCE) || !mgf.ReadASN1ObjectIdentifier(&mgfOid) || !mgfOid.Equal(oidMGF1) {
+			return 0, 0, 0, false
+		}
+		if mgfHash, ok = readHashAlgorithm(&mgf); !ok {
+			return 0, 0, 0, false
+		}
+	}
+	if !seq.ReadOptionalASN1(&field, &present, asn1_.Tag(2).Constructed().ContextSpecific()) {
+		return 0, 0, 0, false
+	}
+	if present && !field.ReadASN1Integer(&saltLength) {
+		return 0, 0, 0, false
+	}
+	if !seq.ReadOptionalASN1(&field, &present, asn1_.Tag(3).Constructed().ContextSpecific()) {
+		return 0, 0, 0, false
+	}
+	if present {
+		var trailer int
+		if !field.ReadASN1Integer(&trailer) || trailer != 1 {
+			return 0, 0, 0, false
+		}
+	}
+	return hash, mgfHash, saltLength, seq.Empty()
+}
+
+func readHashAlgorithm(s *cryptobyte.String) (crypto.Hash, bool) {
+	var algorithm cryptobyte.String
+	var oid asn1.ObjectIdentifier
+	if !s.ReadASN1(&algorithm, asn1_.SEQUENCE) || !algorithm.ReadASN1ObjectIdentifier(&oid) {
+		return 0, false
+	}
+	if !isAbsentOrNull(algorithm) {
+		return 0, false
+	}
+	for hash, v := range oidHashes {
+		if v.Equal(oid) {
+			return hash, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package primus
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"github.com/samber/lo"
+	"golang.org/x/crypto/cryptobyte"
+	asn1_ "golang.org/x/crypto/cryptobyte/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestRSAAuthorizationToken(t *testing.T) {
+	priv := lo.Must(rsa.GenerateKey(rand.Reader, 2048))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.UNBLOCK, nil, "rsa_key")
+
+	for _, alg := range []SignAlgT{
+		RsaSignAlg.SHA256withRSA, RsaSignAlg.SHA384withRSA, RsaSignAlg.SHA512withRSA,
+		RsaSignAlg.SHA256withRSAPSS, RsaSignAlg.SHA384withRSAPSS, RsaSignAlg.SHA512withRSAPSS,
+	} {
+		token := lo.Must(approval.Authorize(priv, alg))
+		if got := ExtractSignAlgorithm(lo.Must(token.GetDerSignatureBytes())); got != alg {
+			t.Fatal("invalid algorithm", alg, got)
+		}
+		verified, err := VerifyAuthorizationToken(token.GetEncoding(), nil)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		if verified.Algorithm != alg || !priv.PublicKey.Equal(verified.PublicKey) {
+			t.Fatal("invalid result", alg)
+		}
+	}
+
+	// a PKCS#1 v1.5 signature doesn't verify as PSS
+	token := lo.Must(approval.Authorize(priv, RsaSignAlg.SHA256withRSA))
+	forged := NewPrimusAuthorizationTokenEncode(approval.Serialize(), lo.Must(token.GetVerifySignatureBytes()),
+		RsaSignAlg.SHA256withRSAPSS, lo.Must(token.GetPublicKeyEncodedBytes()), false)
+	var sigErr SignatureError
+	if _, err := VerifyAuthorizationToken(forged.GetEncoding(), nil); !errors.As(err, &sigErr) {
+		t.Fatal("expected SignatureError, got", err)
+	}
+
+	small := lo.Must(rsa.GenerateKey(rand.Reader, 1024))
+	if _, err := approval.Authorize(small, RsaSignAlg.SHA256withRSA); err == nil {
+		t.Fatal("small keys must be rejected for signing")
+	}
+}
+
+func TestPSSParametersInterop(t *testing.T) {
+	priv := lo.Must(rsa.GenerateKey(rand.Reader, 2048))
+	for alg, sigAlg := range map[SignAlgT]x509.SignatureAlgorithm{
+		RsaSignAlg.SHA256withRSAPSS: x509.SHA256WithRSAPSS,
+		RsaSignAlg.SHA384withRSAPSS: x509.SHA384WithRSAPSS,
+		RsaSignAlg.SHA512withRSAPSS: x509.SHA512WithRSAPSS,
+	} {
+		template := &x509.Certificate{
+			SerialNumber:       big.NewInt(1),
+			Subject:            pkix.Name{CommonName: "pss"},
+			NotBefore:          time.Now(),
+			NotAfter:           time.Now().Add(time.Hour),
+			SignatureAlgorithm: sigAlg,
+		}
+		der := lo.Must(x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv))
+
+		// Certificate ::= SEQUENCE { tbsCertificate, signatureAlgorithm, signatureValue }
+		var input = cryptobyte.String(der)
+		var certificate, algorithmIdentifier cryptobyte.String
+		if !input.ReadASN1(&certificate, asn1_.SEQUENCE) || !certificate.SkipASN1(asn1_.SEQUENCE) ||
+			!certificate.ReadASN1Element(&algorithmIdentifier, asn1_.SEQUENCE) {
+			t.Fatal("invalid certificate")
+		}
+		obj := FindSignatureAlgorithm(alg)
+		expected := lo.Must(encodeDerSignature(obj, nil))
+		if !bytes.Contains(expected, algorithmIdentifier) {
+			t.Fatal("AlgorithmIdentifier differs from crypto/x509", alg)
+		}
+	}
+	if FindSignatureAlgorithmByOID(oidSignatureRSAPSS, nil) != nil {
+		t.Fatal("PSS requires parameters")
+	}
+}
@@ -0,0 +1,215 @@
+package primus
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/cryptobyte"
+	asn1_ "golang.org/x/crypto/cryptobyte/asn1"
+	"hash"
+	"io"
+	"sync"
+)
+
+// SignatureAlgorithm is an approval signature scheme as identified by the AlgorithmIdentifier of
+// DER_SIGNATURE.
+type SignatureAlgorithm interface {
+	Name() SignAlgT
+	OID() asn1.ObjectIdentifier
+	// Parameters returns the DER encoded AlgorithmIdentifier parameters, nil if absent.
+	Parameters() []byte
+	// CheckKey reports whether pub may be used to create new signatures with the algorithm.
+	CheckKey(pub crypto.PublicKey) error
+	// SignMessage signs input, which is hashed by the algorithm if needed.
+	SignMessage(signer crypto.Signer, input []byte) ([]byte, error)
+	// VerifyMessage returns an UnsupportedKeyError if pub can't be used with the algorithm and a
+	// SignatureError if sig is invalid.
+	VerifyMessage(pub crypto.PublicKey, input, sig []byte) error
+}
+
+// DigestAlgorithm is implemented by algorithms signing a digest of the message, so the message
+// can be hashed once for several signers or hashed while it's streamed.
+type DigestAlgorithm interface {
+	SignatureAlgorithm
+	// Digest hashes the message read from r.
+	Digest(r io.Reader) ([]byte, error)
+	SignDigest(signer crypto.Signer, digest []byte) ([]byte, error)
+	VerifyDigest(pub crypto.PublicKey, digest, sig []byte) error
+}
+
+// SignReader signs the message read from r. Only algorithms which aren't a DigestAlgorithm read
+// the whole message into memory.
+func SignReader(alg SignatureAlgorithm, signer crypto.Signer, r io.Reader) ([]byte, error) {
+	if obj, ok := alg.(DigestAlgorithm); ok {
+		digest, err := obj.Digest(r)
+		if err != nil {
+			return nil, err
+		}
+		return obj.SignDigest(signer, digest)
+	}
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return alg.SignMessage(signer, input)
+}
+
+// VerifyReader verifies sig over the message read from r, see SignReader.
+func VerifyReader(alg SignatureAlgorithm, pub crypto.PublicKey, r io.Reader, sig []byte) error {
+	if obj, ok := alg.(DigestAlgorithm); ok {
+		digest, err := obj.Digest(r)
+		if err != nil {
+			return err
+		}
+		return obj.VerifyDigest(pub, digest, sig)
+	}
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return alg.VerifyMessage(pub, input, sig)
+}
+
+func hashReader(h hash.Hash, r io.Reader) ([]byte, error) {
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func checkDigestSize(h hash.Hash, digest []byte) error {
+	if len(digest) != h.Size() {
+		return fmt.Errorf("digest must be %d bytes, got %d", h.Size(), len(digest))
+	}
+	return nil
+}
+
+// parameterMatcher is implemented by algorithms accepting several encodings of their parameters.
+type parameterMatcher interface {
+	MatchParameters(params []byte) bool
+}
+
+type signatureRegistry struct {
+	mu   sync.RWMutex
+	algs []SignatureAlgorithm
+}
+
+var signatureAlgorithms = new(signatureRegistry)
+
+// RegisterSignatureAlgorithm makes alg available for encoding, decoding, signing and verifying
+// authorization tokens. Names and OID/parameter pairs must be unique.
+func RegisterSignatureAlgorithm(alg SignatureAlgorithm) error {
+	signatureAlgorithms.mu.Lock()
+	defer signatureAlgorithms.mu.Unlock()
+	for _, v := range signatureAlgorithms.algs {
+		if v.Name() == alg.Name() {
+			return fmt.Errorf("signature algorithm %s already registered", alg.Name())
+		}
+		if v.OID().Equal(alg.OID()) && bytes.Equal(v.Parameters(), alg.Parameters()) {
+			return fmt.Errorf("signature algorithm %s: OID %s already registered by %s", alg.Name(), alg.OID(), v.Name())
+		}
+	}
+	signatureAlgorithms.algs = append(signatureAlgorithms.algs, alg)
+	return nil
+}
+
+func mustRegisterSignatureAlgorithm(algs ...SignatureAlgorithm) {
+	for _, alg := range algs {
+		if err := RegisterSignatureAlgorithm(alg); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// SignatureAlgorithms returns all registered algorithms in registration order.
+func SignatureAlgorithms() []SignatureAlgorithm {
+	signatureAlgorithms.mu.RLock()
+	defer signatureAlgorithms.mu.RUnlock()
+	return append([]SignatureAlgorithm(nil), signatureAlgorithms.algs...)
+}
+
+func FindSignatureAlgorithm(name SignAlgT) SignatureAlgorithm {
+	signatureAlgorithms.mu.RLock()
+	defer signatureAlgorithms.mu.RUnlock()
+	for _, v := range signatureAlgorithms.algs {
+		if v.Name() == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// FindSignatureAlgorithmByOID looks up an algorithm by OID and DER parameters. An absent and a
+// NULL parameter are treated alike, as encoders disagree on them.
+func FindSignatureAlgorithmByOID(oid asn1.ObjectIdentifier, params []byte) SignatureAlgorithm {
+	signatureAlgorithms.mu.RLock()
+	defer signatureAlgorithms.mu.RUnlock()
+	var candidate SignatureAlgorithm
+	for _, v := range signatureAlgorithms.algs {
+		if !v.OID().Equal(oid) {
+			continue
+		}
+		if bytes.Equal(v.Parameters(), params) {
+			return v
+		}
+		if m, ok := v.(parameterMatcher); ok {
+			if m.MatchParameters(params) {
+				candidate = v
+			}
+		} else if isAbsentOrNull(v.Parameters()) && isAbsentOrNull(params) {
+			candidate = v
+		}
+	}
+	return candidate
+}
+
+func isAbsentOrNull(params []byte) bool {
+	return len(params) == 0 || bytes.Equal(params, asn1.NullBytes)
+}
+
+// derSignature is the DER_SIGNATURE structure:
+// SEQUENCE { AlgorithmIdentifier, BIT STRING signature }
+type derSignature struct {
+	oid       asn1.ObjectIdentifier
+	params    []byte
+	signature []byte
+}
+
+func parseDerSignature(data []byte) (*derSignature, error) {
+	var input = cryptobyte.String(data)
+	var outer, algorithm cryptobyte.String
+	var ret = new(derSignature)
+	if !input.ReadASN1(&outer, asn1_.SEQUENCE) || !outer.ReadASN1(&algorithm, asn1_.SEQUENCE) ||
+		!algorithm.ReadASN1ObjectIdentifier(&ret.oid) {
+		return nil, errors.New("invalid signature algorithm identifier")
+	}
+	if len(algorithm) > 0 {
+		ret.params = []byte(algorithm)
+	}
+	var bitString asn1.BitString
+	if !outer.ReadASN1BitString(&bitString) || bitString.BitLength%8 != 0 {
+		return nil, errors.New("invalid signature bit string")
+	}
+	ret.signature = bitString.Bytes
+	return ret, nil
+}
+
+func (s *derSignature) algorithm() SignatureAlgorithm {
+	return FindSignatureAlgorithmByOID(s.oid, s.params)
+}
+
+func encodeDerSignature(alg SignatureAlgorithm, sig []byte) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
+		b.AddASN1(asn1_.SEQUENCE, func(child *cryptobyte.Builder) {
+			child.AddASN1ObjectIdentifier(alg.OID())
+			if params := alg.Parameters(); len(params) > 0 {
+				child.AddBytes(params)
+			}
+		})
+		b.AddASN1BitString(sig)
+	})
+	return b.Bytes()
+}
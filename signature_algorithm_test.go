@@ -0,0 +1,185 @@
+package primus
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"github.com/samber/lo"
+	"slices"
+	"testing"
+)
+
+// hmacAlgorithm is a toy algorithm where the "public key" is the secret.
+type hmacAlgorithm struct{}
+
+type hmacKey []byte
+
+func (hmacAlgorithm) Name() SignAlgT { return "HmacSHA256" }
+func (hmacAlgorithm) OID() asn1.ObjectIdentifier {
+	return asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+}
+func (hmacAlgorithm) Parameters() []byte                  { return nil }
+func (hmacAlgorithm) CheckKey(pub crypto.PublicKey) error { return nil }
+func (hmacAlgorithm) SignMessage(signer crypto.Signer, input []byte) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+func (a hmacAlgorithm) VerifyMessage(pub crypto.PublicKey, input, sig []byte) error {
+	mac := hmac.New(sha256.New, pub.(hmacKey))
+	mac.Write(input)
+	if !hmac.Equal(mac.Sum(nil), sig) {
+		return SignatureError{Algorithm: a.Name()}
+	}
+	return nil
+}
+
+// registerTestSignatureAlgorithm registers alg for the duration of the test.
+func registerTestSignatureAlgorithm(t *testing.T, alg SignatureAlgorithm) {
+	lo.Must0(RegisterSignatureAlgorithm(alg))
+	t.Cleanup(func() {
+		signatureAlgorithms.mu.Lock()
+		defer signatureAlgorithms.mu.Unlock()
+		signatureAlgorithms.algs = slices.DeleteFunc(signatureAlgorithms.algs, func(v SignatureAlgorithm) bool {
+			return v.Name() == alg.Name()
+		})
+	})
+}
+
+func TestRegisterSignatureAlgorithm(t *testing.T) {
+	registerTestSignatureAlgorithm(t, hmacAlgorithm{})
+	if err := RegisterSignatureAlgorithm(hmacAlgorithm{}); err == nil {
+		t.Fatal("duplicate registration must fail")
+	}
+	if FindSignatureAlgorithm("HmacSHA256") == nil {
+		t.Fatal("not registered")
+	}
+
+	der := DerifyOidAndSig("HmacSHA256", []byte{1, 2, 3})
+	if ExtractSignAlgorithm(der) != "HmacSHA256" {
+		t.Fatal("invalid algorithm")
+	}
+	// no parameters must be encoded for this algorithm
+	if !bytes.Equal(der[2:15], []byte{0x30, 0x0a, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x09, 0x03}) {
+		t.Fatal("invalid encoding", der)
+	}
+	if DerifyOidAndSig("unknown", []byte{1}) != nil {
+		t.Fatal("unknown algorithm must be rejected")
+	}
+	if _, err := EncodePrimusAuthorizationToken([]byte{1}, []byte{1}, "unknown", nil, false); err == nil {
+		t.Fatal("unknown algorithm must be rejected")
+	}
+
+	// ECDSA OIDs are found with NULL and absent parameters
+	ecdsaOid := asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	if FindSignatureAlgorithmByOID(ecdsaOid, nil) != FindSignatureAlgorithmByOID(ecdsaOid, asn1.NullBytes) {
+		t.Fatal("NULL and absent parameters must match")
+	}
+}
+
+func TestSHA3withECDSA(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P384(), rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("payload"), "key")
+	for _, alg := range []SignAlgT{EcdsaSignAlg.SHA3_384withECDSA, EcdsaSignAlg.SHA3_512withECDSA} {
+		token := lo.Must(approval.Authorize(priv, alg))
+		verified, err := VerifyAuthorizationToken(token.GetEncoding(), nil)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		if verified.Algorithm != alg {
+			t.Fatal("invalid algorithm", verified.Algorithm)
+		}
+	}
+	der := lo.Must(lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA3_384withECDSA)).GetDerSignatureBytes())
+	if oid := lo.Must(parseDerSignature(der)).oid; oid.String() != "2.16.840.1.101.3.4.3.11" {
+		t.Fatal("invalid OID", oid)
+	}
+}
+
+func TestSignReader(t *testing.T) {
+	ecKey := lo.Must(ecdsa.GenerateKey(elliptic.P384(), rand.Reader))
+	rsaKey := lo.Must(rsa.GenerateKey(rand.Reader, 2048))
+	message := bytes.Repeat([]byte("large EKA payload "), 1<<18)
+
+	for _, v := range []struct {
+		alg    SignAlgT
+		signer crypto.Signer
+	}{
+		{EcdsaSignAlg.SHA384withECDSA, ecKey},
+		{EcdsaSignAlg.SHA3_512withECDSA, lo.Must(NewDeterministicSigner(ecKey))},
+		{RsaSignAlg.SHA256withRSAPSS, rsaKey},
+	} {
+		obj := FindSignatureAlgorithm(v.alg)
+		sig := lo.Must(SignReader(obj, v.signer, bytes.NewReader(message)))
+		if err := obj.VerifyMessage(v.signer.Public(), message, sig); err != nil {
+			t.Fatal(v.alg, err)
+		}
+		if err := VerifyReader(obj, v.signer.Public(), bytes.NewReader(message[1:]), sig); err == nil {
+			t.Fatal("expected signature error", v.alg)
+		}
+		if _, err := obj.(DigestAlgorithm).SignDigest(v.signer, []byte("short")); err == nil {
+			t.Fatal("expected digest size error", v.alg)
+		}
+	}
+}
+
+func TestNewECDSAObjectSigner(t *testing.T) {
+	obj := NewECDSAObject("SHA256withECDSA", []byte{42, 134, 72, 206, 61, 4, 3, 2}, sha256.New)
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	// crypto.Signer implementations other than *ecdsa.PrivateKey need the hash function
+	sig := lo.Must(obj.SignMessage(lo.Must(NewDeterministicSigner(priv)), []byte("message")))
+	lo.Must0(obj.VerifyMessage(&priv.PublicKey, []byte("message"), sig))
+}
+
+func TestAuthorizeDigest(t *testing.T) {
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, bytes.Repeat([]byte{7}, 1<<20), "gt_ec_08")
+	digested := lo.Must(approval.Digest(EcdsaSignAlg.SHA256withECDSA))
+	if expected := sha256.Sum256(approval.Serialize()); !bytes.Equal(digested.Digest(), expected[:]) {
+		t.Fatal("invalid digest")
+	}
+	for range 3 {
+		priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+		token := lo.Must(digested.Authorize(priv))
+		impl := lo.Must(NewPrimusAuthorizationTokenImpl(token.GetEncoding()))
+		if _, err := impl.VerifyDigest(digested, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := impl.Verify(nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := VerifyAuthorizationTokenReader(bytes.NewReader(token.GetEncoding()), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := approval.Digest(EddsaSignAlg.Ed25519); err == nil {
+		t.Fatal("Ed25519 doesn't sign digests")
+	}
+	fromBytes := lo.Must(DigestBytes(approval.Serialize(), EcdsaSignAlg.SHA256withECDSA))
+	if !bytes.Equal(fromBytes.Digest(), digested.Digest()) {
+		t.Fatal("digests differ")
+	}
+
+	// a token signing another approval doesn't pass for the digested one
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	forged := NewPrimusApprovalToken(ApprovalTokenOp.SIGN, []byte("forged"), "gt_ec_08")
+	token := lo.Must(forged.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+	impl := lo.Must(NewPrimusAuthorizationTokenImpl(token.GetEncoding()))
+	if _, err := impl.VerifyDigest(digested, nil); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatal("expected digest mismatch, got", err)
+	}
+
+	// the same approval signed with another algorithm
+	sha384 := lo.Must(approval.Digest(EcdsaSignAlg.SHA384withECDSA))
+	impl = lo.Must(NewPrimusAuthorizationTokenImpl(lo.Must(digested.Authorize(priv)).GetEncoding()))
+	if _, err := impl.VerifyDigest(sha384, nil); err == nil {
+		t.Fatal("expected algorithm mismatch")
+	}
+	if _, err := VerifyAuthorizationTokenReader(bytes.NewReader(token.GetEncoding()[:20]), nil); err == nil {
+		t.Fatal("expected error for truncated token")
+	}
+}
@@ -0,0 +1,118 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/donutnomad/primus"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error is an error answered by the simulator server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("simulator: %s (%d)", e.Message, e.StatusCode)
+}
+
+// Unwrap returns the error of the simulator the message starts with, so errors.Is works with
+// the errors of this package.
+func (e *Error) Unwrap() error {
+	for _, v := range errorStatus {
+		if v.status == e.StatusCode && strings.HasPrefix(e.Message, v.err.Error()) {
+			return v.err
+		}
+	}
+	return nil
+}
+
+// Client is the primus.HSM of a simulator served by Simulator.Handler.
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+// NewClient connects to the simulator at baseURL, httpClient defaults to http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: strings.TrimSuffix(baseURL, "/"), http: httpClient}
+}
+
+func (c *Client) IntegrityKey(ctx context.Context) (*primus.ParsedPublicKey, error) {
+	return c.publicKey(ctx, "", "/integrity-key")
+}
+
+func (c *Client) CreateKey(ctx context.Context, template *primus.KeyTemplate) (*primus.ParsedPublicKey, error) {
+	body, err := encodeKeyTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.do(ctx, http.MethodPost, "/keys", body)
+	if err != nil {
+		return nil, err
+	}
+	return primus.NewParsedPublicKey(template.Label, data)
+}
+
+func (c *Client) Key(ctx context.Context, label string) (*primus.KeyTemplate, error) {
+	data, err := c.do(ctx, http.MethodGet, "/keys/"+url.PathEscape(label), nil)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeyTemplate(data)
+}
+
+func (c *Client) PublicKey(ctx context.Context, label string) (*primus.ParsedPublicKey, error) {
+	return c.publicKey(ctx, label, "/keys/"+url.PathEscape(label)+"/public-key")
+}
+
+func (c *Client) publicKey(ctx context.Context, name, path string) (*primus.ParsedPublicKey, error) {
+	data, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return primus.NewParsedPublicKey(name, data)
+}
+
+func (c *Client) RequestApproval(ctx context.Context, operation primus.ApprovalTokenOpType, label string, payload []byte) ([]byte, error) {
+	return c.do(ctx, http.MethodPost, "/approvals", primus.NewPrimusApprovalToken(operation, payload, label).Serialize())
+}
+
+func (c *Client) Execute(ctx context.Context, tokens []*primus.AuthorizationToken) ([]byte, error) {
+	result, err := c.do(ctx, http.MethodPost, "/execute", primus.EncodeAuthorizationTokens(tokens))
+	if len(result) == 0 {
+		return nil, err
+	}
+	return result, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	return data, nil
+}
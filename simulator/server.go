@@ -0,0 +1,121 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/donutnomad/primus"
+	"io"
+	"net/http"
+)
+
+const maxRequestSize = 1 << 20
+
+// errorStatus maps the errors of the simulator to HTTP status codes, Client maps them back.
+var errorStatus = []struct {
+	err    error
+	status int
+}{
+	{ErrUnknownKey, http.StatusNotFound},
+	{ErrUnknownApproval, http.StatusNotFound},
+	{ErrKeyExists, http.StatusConflict},
+	{ErrApprovalUsed, http.StatusConflict},
+	{ErrKeyBlocked, http.StatusForbidden},
+	{ErrNoAccess, http.StatusForbidden},
+	{ErrOperationNotAllowed, http.StatusForbidden},
+	{ErrQuorumNotReached, http.StatusForbidden},
+	{ErrDelayNotElapsed, http.StatusForbidden},
+	{ErrTimeLimitExceeded, http.StatusForbidden},
+}
+
+// Handler serves s over HTTP for Client. Bodies carry the Primus encodings, except for key templates
+// which are JSON:
+//
+//	GET  /integrity-key           DER public key
+//	POST /keys                    key template, returns the DER public key
+//	GET  /keys/{label}            key template with the current attributes and Access
+//	GET  /keys/{label}/public-key DER public key
+//	POST /approvals               approval token without timestamp, returns the issued one
+//	POST /execute                 EncodeAuthorizationTokens, returns the result
+//
+// Errors are answered with a status code and the error message as text.
+func (s *Simulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /integrity-key", func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, s.IntegrityKey().GetEncoded(), nil)
+	})
+	mux.HandleFunc("POST /keys", handleRequest(func(body []byte) ([]byte, error) {
+		template, err := decodeKeyTemplate(body)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := s.CreateKey(template)
+		if err != nil {
+			return nil, err
+		}
+		return publicKey.GetEncoded(), nil
+	}))
+	mux.HandleFunc("GET /keys/{label}", func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.Key(r.PathValue("label"))
+		if err != nil {
+			writeResponse(w, nil, err)
+			return
+		}
+		data, err := encodeKeyTemplate(key.Template())
+		writeResponse(w, data, err)
+	})
+	mux.HandleFunc("GET /keys/{label}/public-key", func(w http.ResponseWriter, r *http.Request) {
+		key, err := s.Key(r.PathValue("label"))
+		if err != nil {
+			writeResponse(w, nil, err)
+			return
+		}
+		writeResponse(w, key.PublicKey.GetEncoded(), nil)
+	})
+	mux.HandleFunc("POST /approvals", handleRequest(func(body []byte) ([]byte, error) {
+		request := new(primus.ApprovalToken)
+		if err := request.Deserialize(body); err != nil {
+			return nil, fmt.Errorf("approval request: %w", err)
+		}
+		token, err := s.RequestApproval(request.Operation, request.KeyName, request.EkaPayload)
+		if err != nil {
+			return nil, err
+		}
+		return token.Serialize(), nil
+	}))
+	mux.HandleFunc("POST /execute", handleRequest(func(body []byte) ([]byte, error) {
+		tokens, err := primus.DecodeAuthorizationTokens(body)
+		if err != nil {
+			return nil, err
+		}
+		return s.Execute(tokens...)
+	}))
+	return mux
+}
+
+func handleRequest(fn func(body []byte) ([]byte, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		data, err := fn(body)
+		writeResponse(w, data, err)
+	}
+}
+
+func writeResponse(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		status := http.StatusBadRequest
+		for _, v := range errorStatus {
+			if errors.Is(err, v.err) {
+				status = v.status
+				break
+			}
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"github.com/donutnomad/primus"
+	"github.com/samber/lo"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// approve requests operation on hsm and executes it with the approvals of approvers.
+func approve(hsm primus.HSM, operation primus.ApprovalTokenOpType, label string, payload []byte, approvers ...*testApprover) ([]byte, error) {
+	ctx := context.Background()
+	approval, err := hsm.RequestApproval(ctx, operation, label, payload)
+	if err != nil {
+		return nil, err
+	}
+	tokens := lo.Map(approvers, func(item *testApprover, _ int) *primus.AuthorizationToken {
+		return lo.Must(primus.AuthorizeBytes(approval, item.signer, primus.EcdsaSignAlg.SHA256withECDSA))
+	})
+	return hsm.Execute(ctx, tokens)
+}
+
+func TestClient(t *testing.T) {
+	now := time.Now()
+	sim := lo.Must(New(&Options{Now: func() time.Time { return now }}))
+	server := httptest.NewServer(sim.Handler())
+	defer server.Close()
+	var hsm primus.HSM = NewClient(server.URL, nil)
+	ctx := context.Background()
+	approvers := newTestApprovers(3)
+
+	if integrityKey := lo.Must(hsm.IntegrityKey(ctx)); !integrityKey.Equal(sim.IntegrityKey()) {
+		t.Fatal("unexpected integrity key")
+	}
+	publicKey := lo.Must(hsm.CreateKey(ctx, &primus.KeyTemplate{Label: "wallet 1", Algorithm: primus.KeyAlg.SECP256R1, Access: newTestAccess(approvers, 2, time.Hour)}))
+	if !lo.Must(hsm.PublicKey(ctx, "wallet 1")).Equal(publicKey) {
+		t.Fatal("unexpected public key")
+	}
+	if _, err := hsm.CreateKey(ctx, &primus.KeyTemplate{Label: "wallet 1", Algorithm: primus.KeyAlg.SECP256R1}); !errors.Is(err, ErrKeyExists) {
+		t.Fatal("expected existing key", err)
+	}
+	if _, err := hsm.Key(ctx, "missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("expected unknown key", err)
+	}
+
+	approval := lo.Must(hsm.RequestApproval(ctx, primus.ApprovalTokenOp.SIGN, "wallet 1", []byte("message")))
+	token := new(primus.ApprovalToken)
+	lo.Must0(token.Deserialize(approval))
+	lo.Must0(token.VerifyTimestamp(sim.IntegrityKey().PublicKey()))
+	if _, err := hsm.Execute(ctx, []*primus.AuthorizationToken{lo.Must(primus.AuthorizeBytes(approval, approvers[0].signer, primus.EcdsaSignAlg.SHA256withECDSA))}); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatal("expected missing quorum", err)
+	}
+	signature := lo.Must(approve(hsm, primus.ApprovalTokenOp.SIGN, "wallet 1", []byte("message"), approvers[0], approvers[1]))
+	lo.Must0(primus.FindSignatureAlgorithm(primus.EcdsaSignAlg.SHA256withECDSA).VerifyMessage(publicKey.PublicKey(), []byte("message"), signature))
+
+	if result := lo.Must(approve(hsm, primus.ApprovalTokenOp.BLOCK, "wallet 1", nil, approvers[1], approvers[2])); result != nil {
+		t.Fatal("unexpected result", result)
+	}
+	if _, err := approve(hsm, primus.ApprovalTokenOp.SIGN, "wallet 1", []byte("message"), approvers[0], approvers[1]); !errors.Is(err, ErrKeyBlocked) {
+		t.Fatal("expected blocked key", err)
+	}
+
+	approval = lo.Must(hsm.RequestApproval(ctx, primus.ApprovalTokenOp.MODIFY, "wallet 1", newTestAccess(approvers[:2], 1, 0).ToModifyPayload()))
+	tokens := lo.Map(approvers[:2], func(item *testApprover, _ int) *primus.AuthorizationToken {
+		return lo.Must(primus.AuthorizeBytes(approval, item.signer, primus.EcdsaSignAlg.SHA256withECDSA))
+	})
+	if _, err := hsm.Execute(ctx, tokens); !errors.Is(err, ErrDelayNotElapsed) {
+		t.Fatal("expected delay", err)
+	}
+	now = now.Add(time.Hour)
+	lo.Must(hsm.Execute(ctx, tokens))
+	key := lo.Must(hsm.Key(ctx, "wallet 1"))
+	if !key.Attributes.Get(primus.KeyAttribute.AccessBlocked) || len(key.Access.Sign[0].Groups[0].PublicKeys) != 2 {
+		t.Fatal("unexpected key state", key.Attributes)
+	}
+}
+
+func TestKeyTemplateEncoding(t *testing.T) {
+	template := &primus.KeyTemplate{
+		Label:          "btc_01",
+		Algorithm:      primus.KeyAlg.SECP256K1,
+		Attributes:     primus.KeyAttributes{primus.KeyAttribute.CapabilityDecrypt: false, primus.KeyAttribute.AccessNoPublicKey: true},
+		Access:         newTestAccess(newTestApprovers(2), 1, time.Hour),
+		Cryptocurrency: "BTC",
+	}
+	decoded := lo.Must(decodeKeyTemplate(lo.Must(encodeKeyTemplate(template))))
+	if decoded.Label != "btc_01" || decoded.Algorithm != primus.KeyAlg.SECP256K1 || decoded.Cryptocurrency != "BTC" ||
+		decoded.Attributes.String() != template.Attributes.String() {
+		t.Fatal("invalid template", decoded)
+	}
+	if len(decoded.Access.Sign) != 1 || len(decoded.Access.Sign[0].Groups[0].PublicKeys) != 2 {
+		t.Fatal("invalid access", decoded.Access)
+	}
+	if _, err := decodeKeyTemplate([]byte(`{"label":"k","algorithm":"SECP256R1","attributes":"ACCESS_UNKNOWN"}`)); err == nil {
+		t.Fatal("expected unknown attribute error")
+	}
+}
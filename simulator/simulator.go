@@ -0,0 +1,390 @@
+// Package simulator models a Primus HSM in memory, for tests of code using Extended Key
+// Authorization without a device.
+package simulator
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/donutnomad/primus"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown key")
+var ErrKeyExists = errors.New("key already exists")
+var ErrKeyBlocked = errors.New("key is blocked")
+var ErrNoAccess = errors.New("key has no access policy")
+var ErrOperationNotAllowed = errors.New("operation not allowed")
+var ErrUnknownApproval = errors.New("approval token not issued by this HSM")
+var ErrApprovalUsed = errors.New("approval token already used")
+var ErrQuorumNotReached = errors.New("quorum not reached")
+var ErrDelayNotElapsed = errors.New("delay not elapsed")
+var ErrTimeLimitExceeded = errors.New("time limit exceeded")
+
+// DefaultIntegrityKeyName is the label of the integrity key of a simulator.
+const DefaultIntegrityKeyName = "global-integrity-key"
+
+type Options struct {
+	// Now returns the HSM time, defaults to time.Now.
+	Now func() time.Time
+	// IntegrityKeyName defaults to DefaultIntegrityKeyName.
+	IntegrityKeyName string
+	// IntegrityKey signs the timestamps of approval tokens, a P-256 key is generated if nil.
+	IntegrityKey crypto.Signer
+	// IntegrityAlgorithm defaults to the first signature algorithm of the integrity key.
+	IntegrityAlgorithm primus.SignAlgT
+}
+
+// Key is the state of a simulated key.
+type Key struct {
+	Label      string
+	Algorithm  primus.KeyAlgT
+	Attributes primus.KeyAttributes
+	// Cryptocurrency is the currency type of the key template.
+	Cryptocurrency string
+	// Access is the current policy, replaced by a MODIFY operation.
+	Access    *primus.Access
+	PublicKey *primus.ParsedPublicKey
+}
+
+// Template returns the key as KeyTemplate, with its current attributes and Access.
+func (k *Key) Template() *primus.KeyTemplate {
+	return &primus.KeyTemplate{Label: k.Label, Algorithm: k.Algorithm, Attributes: k.Attributes, Access: k.Access, Cryptocurrency: k.Cryptocurrency}
+}
+
+// Blocked reports whether a BLOCK operation blocked the key.
+func (k *Key) Blocked() bool {
+	return k.Attributes.Get(primus.KeyAttribute.AccessBlocked)
+}
+
+type key struct {
+	Key
+	private crypto.PrivateKey
+}
+
+// approval is an issued approval token, until it's executed.
+type approval struct {
+	token    *primus.ApprovalToken
+	issuedAt time.Time
+	used     bool
+}
+
+// Simulator holds keys with their Access policy and executes the EKA operations SIGN, BLOCK,
+// UNBLOCK and MODIFY like the HSM: an operation is requested with RequestApproval, which
+// returns an approval token timestamped by the integrity key. Approvers sign it and Execute
+// runs the operation once one token of the key's AccessBlob for the operation is satisfied,
+// after its delay and within its time limit.
+type Simulator struct {
+	mu               sync.Mutex
+	now              func() time.Time
+	integrityKeyName string
+	integrityKey     crypto.Signer
+	integrityAlg     primus.SignatureAlgorithm
+	integrityPublic  *primus.ParsedPublicKey
+	keys             map[string]*key
+	approvals        map[[32]byte]*approval
+}
+
+func New(opts *Options) (*Simulator, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	s := &Simulator{
+		now:              opts.Now,
+		integrityKeyName: opts.IntegrityKeyName,
+		integrityKey:     opts.IntegrityKey,
+		keys:             make(map[string]*key),
+		approvals:        make(map[[32]byte]*approval),
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	if s.integrityKeyName == "" {
+		s.integrityKeyName = DefaultIntegrityKeyName
+	}
+	if s.integrityKey == nil {
+		private, err := generateKey(primus.KeyAlg.SECP256R1)
+		if err != nil {
+			return nil, err
+		}
+		s.integrityKey = private.(crypto.Signer)
+	}
+	var err error
+	if s.integrityPublic, err = primus.NewParsedPublicKeyFromKey(s.integrityKeyName, s.integrityKey.Public()); err != nil {
+		return nil, fmt.Errorf("integrity key: %w", err)
+	}
+	if opts.IntegrityAlgorithm != "" {
+		s.integrityAlg = primus.FindSignatureAlgorithm(opts.IntegrityAlgorithm)
+		if s.integrityAlg == nil {
+			return nil, fmt.Errorf("unsupported signature algorithm %q", opts.IntegrityAlgorithm)
+		}
+		if err := s.integrityAlg.CheckKey(s.integrityKey.Public()); err != nil {
+			return nil, fmt.Errorf("integrity key: %w", err)
+		}
+	} else if algs := s.integrityPublic.KeyAlg().SignatureAlgorithms(); len(algs) > 0 {
+		s.integrityAlg = algs[0]
+	} else {
+		return nil, errors.New("integrity key can't sign")
+	}
+	return s, nil
+}
+
+// IntegrityKey returns the public key verifying the timestamps of approval tokens.
+func (s *Simulator) IntegrityKey() *primus.ParsedPublicKey {
+	return s.integrityPublic
+}
+
+// CreateKey generates a key as described by template.
+func (s *Simulator) CreateKey(template *primus.KeyTemplate) (*primus.ParsedPublicKey, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[template.Label]; ok || template.Label == s.integrityKeyName {
+		return nil, fmt.Errorf("%w: %s", ErrKeyExists, template.Label)
+	}
+	private, err := generateKey(template.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := primus.NewParsedPublicKeyFromKey(template.Label, private.(interface{ Public() crypto.PublicKey }).Public())
+	if err != nil {
+		return nil, err
+	}
+	access, err := copyAccess(template.Access)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[template.Label] = &key{
+		Key: Key{
+			Label:          template.Label,
+			Algorithm:      template.Algorithm,
+			Attributes:     template.Attributes.WithDefaults(),
+			Cryptocurrency: template.Cryptocurrency,
+			Access:         access,
+			PublicKey:      publicKey,
+		},
+		private: private,
+	}
+	return publicKey, nil
+}
+
+func generateKey(alg primus.KeyAlgT) (crypto.PrivateKey, error) {
+	switch alg {
+	case primus.KeyAlg.SECP256K1:
+		return primus.GenerateSecp256k1Signer()
+	case primus.KeyAlg.ED25519:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case primus.KeyAlg.X25519:
+		return ecdh.X25519().GenerateKey(rand.Reader)
+	}
+	if curve := alg.Curve(); curve != nil {
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
+	return nil, fmt.Errorf("unsupported key algorithm %q", alg)
+}
+
+// Key returns the state of the key called label.
+func (s *Simulator) Key(label string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, err := s.key(label)
+	if err != nil {
+		return nil, err
+	}
+	ret := k.Key
+	ret.Attributes = make(primus.KeyAttributes)
+	for attr, v := range k.Attributes {
+		ret.Attributes[attr] = v
+	}
+	if ret.Access, err = copyAccess(k.Access); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// copyAccess returns a deep copy of access, which shares no state with the keys of the simulator.
+func copyAccess(access *primus.Access) (*primus.Access, error) {
+	if access == nil {
+		return nil, nil
+	}
+	ret := new(primus.Access)
+	if err := ret.Deserialize(access.Serialize()); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *Simulator) key(label string) (*key, error) {
+	k, ok := s.keys[label]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, label)
+	}
+	return k, nil
+}
+
+// RequestApproval issues the approval token of an operation on the key called label. The payload
+// is the message to sign for SIGN and the Access.ToModifyPayload of the new policy for MODIFY.
+func (s *Simulator) RequestApproval(operation primus.ApprovalTokenOpType, label string, payload []byte) (*primus.ApprovalToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, err := s.key(label)
+	if err != nil {
+		return nil, err
+	}
+	if k.Access == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoAccess, label)
+	}
+	switch operation {
+	case primus.ApprovalTokenOp.SIGN:
+		if len(payload) == 0 {
+			return nil, errors.New("missing payload to sign")
+		}
+		if !k.Attributes.Get(primus.KeyAttribute.CapabilitySign) {
+			return nil, fmt.Errorf("%w: key has no %s", ErrOperationNotAllowed, primus.KeyAttribute.CapabilitySign)
+		}
+	case primus.ApprovalTokenOp.BLOCK, primus.ApprovalTokenOp.UNBLOCK:
+		if len(payload) > 0 {
+			return nil, fmt.Errorf("unexpected payload for %s", operation)
+		}
+	case primus.ApprovalTokenOp.MODIFY:
+		if _, err := k.modifiedAccess(payload); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrOperationNotAllowed, operation)
+	}
+	if len(k.Access.GetBlob(operation.ToBlobName())) == 0 {
+		return nil, fmt.Errorf("%w: %s has no access token", ErrOperationNotAllowed, operation.ToBlobName())
+	}
+
+	issuedAt := time.Unix(s.now().Unix(), 0)
+	timestamp := primus.EncodePrimusTimestamp(payload, s.integrityKeyName, issuedAt.Unix())
+	signature, err := s.integrityAlg.SignMessage(s.integrityKey, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	token := primus.NewPrimusApprovalTokenWithTime(operation, payload, label, timestamp, primus.NewPrimusSignature(s.integrityAlg.Name(), signature))
+	s.approvals[sha256.Sum256(token.Serialize())] = &approval{token: token, issuedAt: issuedAt}
+	return token, nil
+}
+
+// modifiedAccess decodes the payload of a MODIFY operation.
+func (k *key) modifiedAccess(payload []byte) (*primus.Access, error) {
+	if !k.Attributes.Get(primus.KeyAttribute.AccessModifiable) {
+		return nil, fmt.Errorf("%w: key isn't %s", ErrOperationNotAllowed, primus.KeyAttribute.AccessModifiable)
+	}
+	access := new(primus.Access)
+	if err := access.Deserialize(payload); err != nil {
+		return nil, fmt.Errorf("modify payload: %w", err)
+	}
+	template := &primus.KeyTemplate{Label: k.Label, Algorithm: k.Algorithm, Attributes: k.Attributes, Access: access}
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// Execute runs the operation of the approval token signed by tokens. The approval token can be
+// executed once, SIGN returns the ASN1 signature of the payload made with the first signature
+// algorithm of the key.
+func (s *Simulator) Execute(tokens ...*primus.AuthorizationToken) ([]byte, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("no authorization token")
+	}
+	bundle := primus.NewAuthorizationBundle(nil)
+	for i, token := range tokens {
+		if err := bundle.Add(token); err != nil {
+			return nil, fmt.Errorf("authorization token %d: %w", i, err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issued, ok := s.approvals[sha256.Sum256(bundle.ApprovalTokenBytes())]
+	if !ok {
+		return nil, ErrUnknownApproval
+	}
+	if issued.used {
+		return nil, ErrApprovalUsed
+	}
+	operation := issued.token.Operation
+	k, err := s.key(issued.token.KeyName)
+	if err != nil {
+		return nil, err
+	}
+	if k.Access == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoAccess, k.Label)
+	}
+	if err := s.checkAccess(k.Access.GetBlob(operation.ToBlobName()), bundle, issued.issuedAt); err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	switch operation {
+	case primus.ApprovalTokenOp.SIGN:
+		if result, err = k.sign(issued.token.EkaPayload); err != nil {
+			return nil, err
+		}
+	case primus.ApprovalTokenOp.BLOCK:
+		k.Attributes[primus.KeyAttribute.AccessBlocked] = true
+	case primus.ApprovalTokenOp.UNBLOCK:
+		k.Attributes[primus.KeyAttribute.AccessBlocked] = false
+	case primus.ApprovalTokenOp.MODIFY:
+		access, err := k.modifiedAccess(issued.token.EkaPayload)
+		if err != nil {
+			return nil, err
+		}
+		k.Access = access
+	}
+	issued.used = true
+	return result, nil
+}
+
+// checkAccess succeeds if a token of blob is satisfied by the signers of bundle and issuedAt plus
+// its delay is passed, but not its time limit. A time limit of 0 doesn't expire.
+func (s *Simulator) checkAccess(blob primus.AccessBlob, bundle *primus.AuthorizationBundle, issuedAt time.Time) error {
+	now := s.now()
+	progress := bundle.Progress(blob)
+	if !progress.Satisfied {
+		return ErrQuorumNotReached
+	}
+	var err error
+	for i, token := range blob {
+		if !progress.Tokens[i].Satisfied {
+			continue
+		}
+		notBefore := issuedAt.Add(time.Duration(token.DelayMs) * time.Millisecond)
+		if now.Before(notBefore) {
+			err = fmt.Errorf("%w: %s left", ErrDelayNotElapsed, notBefore.Sub(now))
+			continue
+		}
+		if token.TimeLimitMs > 0 && now.After(notBefore.Add(time.Duration(token.TimeLimitMs)*time.Millisecond)) {
+			if err == nil {
+				err = ErrTimeLimitExceeded
+			}
+			continue
+		}
+		return nil
+	}
+	return err
+}
+
+func (k *key) sign(payload []byte) ([]byte, error) {
+	if k.Blocked() {
+		return nil, fmt.Errorf("%w: %s", ErrKeyBlocked, k.Label)
+	}
+	signer, ok := k.private.(crypto.Signer)
+	algs := k.Algorithm.SignatureAlgorithms()
+	if !ok || len(algs) == 0 {
+		return nil, fmt.Errorf("%w: %s keys can't sign", ErrOperationNotAllowed, k.Algorithm)
+	}
+	return algs[0].SignMessage(signer, payload)
+}
@@ -0,0 +1,135 @@
+package simulator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/donutnomad/primus"
+	"github.com/samber/lo"
+	"testing"
+	"time"
+)
+
+type testApprover struct {
+	signer    crypto.Signer
+	publicKey *primus.ParsedPublicKey
+}
+
+func newTestApprovers(n int) []*testApprover {
+	return lo.Times(n, func(int) *testApprover {
+		key := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+		return &testApprover{signer: key, publicKey: lo.Must(primus.NewParsedPublicKeyFromKey("", &key.PublicKey))}
+	})
+}
+
+// newTestAccess needs quorum of approvers for every operation, MODIFY after delay.
+func newTestAccess(approvers []*testApprover, quorum int, delay time.Duration) *primus.Access {
+	keys := lo.Map(approvers, func(item *testApprover, _ int) primus.Publickey { return item.publicKey })
+	token := func(delay time.Duration) []*primus.AccessToken {
+		return []*primus.AccessToken{{Name: "t", DelayMs: delay.Milliseconds(), TimeLimitMs: time.Hour.Milliseconds(), Groups: []*primus.AccessGroup{
+			{Name: "ops", Quorum: quorum, PublicKeys: keys},
+		}}}
+	}
+	return primus.NewAccess(token(0), token(0), token(0), token(delay))
+}
+
+func authorize(token *primus.ApprovalToken, approvers ...*testApprover) []*primus.AuthorizationToken {
+	return lo.Map(approvers, func(item *testApprover, _ int) *primus.AuthorizationToken {
+		return lo.Must(token.Authorize(item.signer, primus.EcdsaSignAlg.SHA256withECDSA))
+	})
+}
+
+func TestSimulator(t *testing.T) {
+	now := time.Date(2024, 10, 21, 12, 0, 0, 0, time.UTC)
+	hsm := lo.Must(New(&Options{Now: func() time.Time { return now }}))
+	approvers := newTestApprovers(3)
+	publicKey := lo.Must(hsm.CreateKey(&primus.KeyTemplate{Label: "wallet", Algorithm: primus.KeyAlg.SECP256K1, Access: newTestAccess(approvers, 2, time.Hour)}))
+	if _, err := hsm.CreateKey(&primus.KeyTemplate{Label: "wallet", Algorithm: primus.KeyAlg.SECP256R1}); !errors.Is(err, ErrKeyExists) {
+		t.Fatal("expected existing key", err)
+	}
+
+	approval := lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "wallet", []byte("message")))
+	if issuedAt, ok := approval.Time(); !ok || !issuedAt.Equal(now) {
+		t.Fatal("unexpected timestamp", issuedAt)
+	}
+	lo.Must0(approval.VerifyTimestamp(hsm.IntegrityKey().PublicKey()))
+	if _, err := hsm.Execute(authorize(approval, approvers[0])...); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatal("expected missing quorum", err)
+	}
+	signature := lo.Must(hsm.Execute(authorize(approval, approvers[0], approvers[2])...))
+	lo.Must0(primus.FindSignatureAlgorithm(primus.EcdsaSignAlg.SHA256withECDSA).VerifyMessage(publicKey.PublicKey(), []byte("message"), signature))
+	if _, err := hsm.Execute(authorize(approval, approvers[0], approvers[1])...); !errors.Is(err, ErrApprovalUsed) {
+		t.Fatal("expected used approval", err)
+	}
+	forged := primus.NewPrimusApprovalToken(primus.ApprovalTokenOp.SIGN, []byte("message"), "wallet")
+	if _, err := hsm.Execute(authorize(forged, approvers...)...); !errors.Is(err, ErrUnknownApproval) {
+		t.Fatal("expected unknown approval", err)
+	}
+
+	// the time limit starts after the delay
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.BLOCK, "wallet", nil))
+	now = now.Add(2 * time.Hour)
+	if _, err := hsm.Execute(authorize(approval, approvers[1], approvers[2])...); !errors.Is(err, ErrTimeLimitExceeded) {
+		t.Fatal("expected expired approval", err)
+	}
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.BLOCK, "wallet", nil))
+	lo.Must(hsm.Execute(authorize(approval, approvers[1], approvers[2])...))
+	if key := lo.Must(hsm.Key("wallet")); !key.Blocked() {
+		t.Fatal("key not blocked")
+	}
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "wallet", []byte("message")))
+	if _, err := hsm.Execute(authorize(approval, approvers[1], approvers[2])...); !errors.Is(err, ErrKeyBlocked) {
+		t.Fatal("expected blocked key", err)
+	}
+
+	// a single approver may sign after MODIFY, once the delay passed
+	modify := newTestAccess(approvers, 1, time.Hour)
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.MODIFY, "wallet", modify.ToModifyPayload()))
+	now = now.Add(30 * time.Minute)
+	if _, err := hsm.Execute(authorize(approval, approvers[0], approvers[1])...); !errors.Is(err, ErrDelayNotElapsed) {
+		t.Fatal("expected delay", err)
+	}
+	now = now.Add(30 * time.Minute)
+	lo.Must(hsm.Execute(authorize(approval, approvers[0], approvers[1])...))
+	if key := lo.Must(hsm.Key("wallet")); key.Access.Sign[0].Groups[0].Quorum != 1 || !key.Blocked() {
+		t.Fatal("access not modified")
+	}
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.UNBLOCK, "wallet", nil))
+	lo.Must(hsm.Execute(authorize(approval, approvers[0])...))
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "wallet", []byte("message")))
+	lo.Must(hsm.Execute(authorize(approval, approvers[1])...))
+}
+
+func TestSimulatorRequestApproval(t *testing.T) {
+	hsm := lo.Must(New(nil))
+	approvers := newTestApprovers(2)
+	lo.Must(hsm.CreateKey(&primus.KeyTemplate{Label: "plain", Algorithm: primus.KeyAlg.SECP256R1}))
+	lo.Must(hsm.CreateKey(&primus.KeyTemplate{
+		Label:      "fixed",
+		Algorithm:  primus.KeyAlg.ED25519,
+		Attributes: primus.KeyAttributes{primus.KeyAttribute.AccessModifiable: false},
+		Access:     newTestAccess(approvers, 1, 0),
+	}))
+
+	if _, err := hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "missing", []byte("message")); !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("expected unknown key", err)
+	}
+	if _, err := hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "plain", []byte("message")); !errors.Is(err, ErrNoAccess) {
+		t.Fatal("expected missing access", err)
+	}
+	if _, err := hsm.RequestApproval(primus.ApprovalTokenOp.MODIFY, "fixed", newTestAccess(approvers, 2, 0).ToModifyPayload()); !errors.Is(err, ErrOperationNotAllowed) {
+		t.Fatal("expected unmodifiable key", err)
+	}
+	approval := lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "fixed", []byte("message")))
+	signature := lo.Must(hsm.Execute(authorize(approval, approvers[1])...))
+	key := lo.Must(hsm.Key("fixed"))
+	lo.Must0(primus.FindSignatureAlgorithm(primus.EddsaSignAlg.Ed25519).VerifyMessage(key.PublicKey.PublicKey(), []byte("message"), signature))
+
+	// the returned Access is a copy, changing it doesn't change the policy of the key
+	key.Access.Sign[0].Groups[0].Quorum = 2
+	key.Access.Sign[0].Groups[0].PublicKeys = nil
+	approval = lo.Must(hsm.RequestApproval(primus.ApprovalTokenOp.SIGN, "fixed", []byte("message")))
+	lo.Must(hsm.Execute(authorize(approval, approvers[1])...))
+}
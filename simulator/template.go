@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/donutnomad/primus"
+)
+
+// keyTemplate is the JSON body of primus.KeyTemplate between Client and Handler.
+type keyTemplate struct {
+	Label          string `json:"label"`
+	Algorithm      string `json:"algorithm"`
+	Attributes     string `json:"attributes"`
+	Cryptocurrency string `json:"cryptocurrency,omitempty"`
+	// Access is the Primus encoding of primus.Access.
+	Access []byte `json:"access,omitempty"`
+}
+
+func encodeKeyTemplate(template *primus.KeyTemplate) ([]byte, error) {
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	body := keyTemplate{
+		Label:          template.Label,
+		Algorithm:      template.Algorithm.String(),
+		Attributes:     template.Attributes.String(),
+		Cryptocurrency: template.Cryptocurrency,
+	}
+	if template.Access != nil {
+		body.Access = template.Access.Serialize()
+	}
+	return json.Marshal(body)
+}
+
+func decodeKeyTemplate(data []byte) (*primus.KeyTemplate, error) {
+	var body keyTemplate
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("key template: %w", err)
+	}
+	template := &primus.KeyTemplate{Label: body.Label, Cryptocurrency: body.Cryptocurrency}
+	if !template.Algorithm.FromString(body.Algorithm) {
+		return nil, fmt.Errorf("key template: unknown key algorithm %q", body.Algorithm)
+	}
+	attributes, err := primus.ParseKeyAttributeNames(body.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("key template: %w", err)
+	}
+	template.Attributes = attributes
+	if body.Access != nil {
+		template.Access = new(primus.Access)
+		if err := template.Access.Deserialize(body.Access); err != nil {
+			return nil, fmt.Errorf("key template: %w", err)
+		}
+	}
+	return template, template.Validate()
+}
@@ -124,34 +124,52 @@ func underifyOidAndSig(data []byte) []byte {
 	return res
 }
 
-func DerifyOidAndSig(signAlgorithm EcdsaSignAlgT, sig []byte) []byte {
-	obj := FindEcdsaByName(signAlgorithm)
-	if obj == nil {
-		panic("unreachable code")
+// DerifyOidAndSig wraps sig in the AlgorithmIdentifier of signAlgorithm, it returns nil if the
+// algorithm is not registered.
+func DerifyOidAndSig(signAlgorithm SignAlgT, sig []byte) []byte {
+	alg := FindSignatureAlgorithm(signAlgorithm)
+	if alg == nil {
+		return nil
 	}
-	var b cryptobyte.Builder
-	b.AddASN1(asn1_.SEQUENCE, func(b *cryptobyte.Builder) {
-		b.AddASN1(asn1_.SEQUENCE, func(child *cryptobyte.Builder) {
-			child.AddASN1(asn1_.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) {
-				b.AddBytes(obj.oid)
-			})
-			child.AddASN1NULL()
-		})
-		b.AddASN1BitString(sig)
-	})
-	res, err := b.Bytes()
+	res, err := encodeDerSignature(alg, sig)
 	if err != nil {
 		return nil
 	}
 	return res
 }
 
-func extractSignAlgorithm(sig []byte) EcdsaSignAlgT {
-	return finEcdsaNameByOid(findType(sig, 0, asn1.TagOID))
+func extractSignAlgorithm(sig []byte) SignAlgT {
+	return ExtractSignAlgorithm(sig)
 }
 
-func ExtractSignAlgorithm(sig []byte) EcdsaSignAlgT {
-	return finEcdsaNameByOid(findType(sig, 0, asn1.TagOID))
+// ExtractSignAlgorithm returns the name of the registered algorithm of a DER_SIGNATURE, empty if unknown.
+func ExtractSignAlgorithm(sig []byte) SignAlgT {
+	parsed, err := parseDerSignature(sig)
+	if err != nil {
+		return ""
+	}
+	if alg := parsed.algorithm(); alg != nil {
+		return alg.Name()
+	}
+	return ""
+}
+
+// parseOid decodes the content octets of an OBJECT IDENTIFIER.
+func parseOid(content []byte) (asn1.ObjectIdentifier, bool) {
+	var b cryptobyte.Builder
+	b.AddASN1(asn1_.OBJECT_IDENTIFIER, func(b *cryptobyte.Builder) {
+		b.AddBytes(content)
+	})
+	bs, err := b.Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var oid asn1.ObjectIdentifier
+	var str = cryptobyte.String(bs)
+	if !str.ReadASN1ObjectIdentifier(&oid) {
+		return nil, false
+	}
+	return oid, true
 }
 
 func fillByte(bs []byte, t int) {
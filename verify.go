@@ -0,0 +1,242 @@
+package primus
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrMissingTimestamp = errors.New("approval token has no timestamp")
+var ErrTokenExpired = errors.New("approval token expired")
+var ErrTokenNotYetValid = errors.New("approval token timestamp is in the future")
+var ErrTokenReplayed = errors.New("authorization token already used")
+var ErrDigestMismatch = errors.New("digest doesn't match the approval token")
+var ErrReplayCacheWithoutMaxAge = errors.New("replay cache requires MaxAge, entries would never expire")
+
+// MissingFieldError is returned when a required part of a token is absent.
+type MissingFieldError struct {
+	Field string
+}
+
+func (e MissingFieldError) Error() string { return "authorization token: missing " + e.Field }
+
+// MalformedSignatureError is returned when DER_SIGNATURE can't be decoded.
+type MalformedSignatureError struct {
+	Err error
+}
+
+func (e MalformedSignatureError) Error() string {
+	return "authorization token: malformed signature: " + e.Err.Error()
+}
+
+func (e MalformedSignatureError) Unwrap() error { return e.Err }
+
+// UnknownAlgorithmError is returned when the signature algorithm OID is not registered.
+type UnknownAlgorithmError struct {
+	OID asn1.ObjectIdentifier
+}
+
+func (e UnknownAlgorithmError) Error() string {
+	return "authorization token: unknown signature algorithm " + e.OID.String()
+}
+
+// UnsupportedKeyError is returned when the signer key cannot be parsed or is not usable with the algorithm.
+type UnsupportedKeyError struct {
+	Reason string
+}
+
+func (e UnsupportedKeyError) Error() string {
+	return "authorization token: unsupported key: " + e.Reason
+}
+
+// SignatureError is returned when the signature doesn't verify.
+type SignatureError struct {
+	Algorithm SignAlgT
+}
+
+func (e SignatureError) Error() string {
+	return "authorization token: invalid " + e.Algorithm.String() + " signature"
+}
+
+// VerifyOptions controls how authorization tokens are accepted.
+type VerifyOptions struct {
+	// MaxAge is the maximum age of the HSM timestamp embedded in the approval token.
+	// Zero disables the age check.
+	MaxAge time.Duration
+	// ClockSkew is the tolerated difference between the local clock and the HSM clock.
+	ClockSkew time.Duration
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+	// ReplayCache rejects an approval token that was already accepted for the same signer. It
+	// requires MaxAge, entries are kept until the token expires.
+	ReplayCache ReplayCache
+	// IntegrityKey is the public key of the HSM integrity key. If set, the approval timestamp must
+	// verify against it.
+	IntegrityKey crypto.PublicKey
+	// Roots enables certificate validation: the token must carry a signer certificate chaining to
+	// one of Roots, valid at the approval timestamp if IntegrityKey is set or else at Now.
+	Roots *x509.CertPool
+	// CertificateKeyUsages are the extended key usages accepted for the signer certificate,
+	// defaults to any.
+	CertificateKeyUsages []x509.ExtKeyUsage
+	// Keyring restricts signers to its identities which aren't revoked, the identity name becomes
+	// VerifiedAuthorization.Identity unless a certificate was validated.
+	Keyring *Keyring
+}
+
+func (o *VerifyOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// CheckFreshness enforces MaxAge and the replay cache of opts on the token.
+// The signature must have been verified before, otherwise forged tokens could fill the replay cache.
+func (t *AuthorizationTokenImpl) CheckFreshness(opts *VerifyOptions) error {
+	if opts == nil || (opts.MaxAge <= 0 && opts.ReplayCache == nil) {
+		return nil
+	}
+	if opts.ReplayCache != nil && opts.MaxAge <= 0 {
+		return ErrReplayCacheWithoutMaxAge
+	}
+	now := opts.now()
+	var expiresAt time.Time
+	if opts.MaxAge > 0 {
+		issuedAt, ok := t.ApprovalToken.Time()
+		if !ok {
+			return ErrMissingTimestamp
+		}
+		if issuedAt.After(now.Add(opts.ClockSkew)) {
+			return ErrTokenNotYetValid
+		}
+		expiresAt = issuedAt.Add(opts.MaxAge + opts.ClockSkew)
+		if now.After(expiresAt) {
+			return ErrTokenExpired
+		}
+	}
+	if opts.ReplayCache != nil {
+		fresh, err := opts.ReplayCache.Add(NewReplayKey(t.ApprovalTokenBytes, t.PublicKeyEncodedBytes), now, expiresAt)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrTokenReplayed
+		}
+	}
+	return nil
+}
+
+// VerifiedAuthorization is the result of a successful VerifyAuthorizationToken.
+type VerifiedAuthorization struct {
+	PublicKey          crypto.PublicKey
+	Key                *ParsedPublicKey
+	PublicKeyEncoded   []byte
+	Algorithm          SignAlgT
+	ApprovalTokenBytes []byte
+	ApprovalToken      *ApprovalToken
+	Certificates       []*x509.Certificate
+	// Identity is the subject of the signer certificate once validated against VerifyOptions.Roots,
+	// or else the name of the signer in VerifyOptions.Keyring.
+	Identity string
+}
+
+// VerifyAuthorizationToken parses data, verifies the signature over the approval token with
+// the embedded signer key and applies opts, which may be nil.
+func VerifyAuthorizationToken(data []byte, opts *VerifyOptions) (*VerifiedAuthorization, error) {
+	token, err := NewPrimusAuthorizationTokenImpl(data)
+	if err != nil {
+		return nil, err
+	}
+	return token.Verify(opts)
+}
+
+func (t *AuthorizationTokenImpl) Verify(opts *VerifyOptions) (*VerifiedAuthorization, error) {
+	return t.verify(opts, func(alg SignatureAlgorithm, pub crypto.PublicKey, sig []byte) error {
+		return alg.VerifyMessage(pub, t.ApprovalTokenBytes, sig)
+	})
+}
+
+// VerifyAuthorizationTokenReader is VerifyAuthorizationToken for a token read from r. The whole
+// token is read into memory first.
+func VerifyAuthorizationTokenReader(r io.Reader, opts *VerifyOptions) (*VerifiedAuthorization, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyAuthorizationToken(data, opts)
+}
+
+// VerifyDigest is Verify for a token signing the approval token of d, using the digest of d
+// instead of hashing the approval token again. Tokens signing any other approval token fail
+// with ErrDigestMismatch.
+func (t *AuthorizationTokenImpl) VerifyDigest(d *DigestedApproval, opts *VerifyOptions) (*VerifiedAuthorization, error) {
+	if !bytes.Equal(t.ApprovalTokenBytes, d.bytes) {
+		return nil, ErrDigestMismatch
+	}
+	return t.verify(opts, func(alg SignatureAlgorithm, pub crypto.PublicKey, sig []byte) error {
+		obj, ok := alg.(DigestAlgorithm)
+		if !ok || alg.Name() != d.alg {
+			return fmt.Errorf("token signed with %s, digest made for %s", alg.Name(), d.alg)
+		}
+		return obj.VerifyDigest(pub, d.digest, sig)
+	})
+}
+
+func (t *AuthorizationTokenImpl) verify(opts *VerifyOptions, verifySignature func(alg SignatureAlgorithm, pub crypto.PublicKey, sig []byte) error) (*VerifiedAuthorization, error) {
+	der, err := parseDerSignature(t.DerSignatureBytes)
+	if err != nil {
+		return nil, MalformedSignatureError{Err: err}
+	}
+	alg := der.algorithm()
+	if alg == nil {
+		return nil, UnknownAlgorithmError{OID: der.oid}
+	}
+	key, err := NewParsedPublicKey("", t.PublicKeyEncodedBytes)
+	if err != nil {
+		return nil, UnsupportedKeyError{Reason: err.Error()}
+	}
+	pub := key.PublicKey()
+	if err = verifySignature(alg, pub, der.signature); err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.IntegrityKey != nil {
+		if err := t.ApprovalToken.VerifyTimestamp(opts.IntegrityKey); err != nil {
+			return nil, fmt.Errorf("approval timestamp: %w", err)
+		}
+	}
+	identity, err := t.verifyCertificates(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.Keyring != nil {
+		id := opts.Keyring.findKey(key)
+		if id == nil {
+			return nil, ErrUnknownSigner
+		}
+		if id.IsRevoked(opts.now()) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyRevoked, id.Name)
+		}
+		if len(identity) == 0 {
+			identity = id.Name
+		}
+	}
+	if err := t.CheckFreshness(opts); err != nil {
+		return nil, err
+	}
+	return &VerifiedAuthorization{
+		PublicKey:          pub,
+		Key:                key,
+		PublicKeyEncoded:   t.PublicKeyEncodedBytes,
+		Algorithm:          alg.Name(),
+		ApprovalTokenBytes: t.ApprovalTokenBytes,
+		ApprovalToken:      t.ApprovalToken,
+		Certificates:       t.Certificates,
+		Identity:           identity,
+	}, nil
+}
@@ -0,0 +1,218 @@
+package primus
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"github.com/samber/lo"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testApprovalTokenWithTime = "d00000003b000400010000000210080067745f65635f3038541040003c00000057101200636f6e74656e7420746f206265207369676e00000701080038db15670000000002101400676c6f62616c2d696e746567726974792d6b657956105a003058300c06082a8648ce3d0403020500034800304502206a1682a7afac732ab4ab7f9576ff70880b8414d3ff04448778e1b6ebb877a3d4022100b7b004e29f23454ab13dc73ec0aa70d9b428d656b35465dc6cbaefe38e338a58000057101200636f6e74656e7420746f206265207369676e0000"
+
+func newFreshnessTestToken(publicKey []byte) *AuthorizationTokenImpl {
+	bs := mustDecode(testApprovalTokenWithTime)
+	tt := new(ApprovalToken)
+	lo.Must0(tt.Deserialize(bs))
+	return &AuthorizationTokenImpl{
+		PublicKeyEncodedBytes: publicKey,
+		ApprovalTokenBytes:    bs,
+		ApprovalToken:         tt,
+	}
+}
+
+func TestCheckFreshness(t *testing.T) {
+	token := newFreshnessTestToken([]byte{1})
+	issuedAt, ok := token.ApprovalToken.Time()
+	if !ok || issuedAt.Unix() != 0x6715db38 {
+		t.Fatal("invalid timestamp", issuedAt)
+	}
+
+	var now time.Time
+	opts := &VerifyOptions{
+		MaxAge:    5 * time.Minute,
+		ClockSkew: 30 * time.Second,
+		Now:       func() time.Time { return now },
+	}
+	now = issuedAt.Add(time.Minute)
+	if err := token.CheckFreshness(opts); err != nil {
+		t.Fatal(err)
+	}
+	now = issuedAt.Add(-20 * time.Second)
+	if err := token.CheckFreshness(opts); err != nil {
+		t.Fatal(err)
+	}
+	now = issuedAt.Add(-time.Minute)
+	if err := token.CheckFreshness(opts); !errors.Is(err, ErrTokenNotYetValid) {
+		t.Fatal("expected not yet valid, got", err)
+	}
+	now = issuedAt.Add(6 * time.Minute)
+	if err := token.CheckFreshness(opts); !errors.Is(err, ErrTokenExpired) {
+		t.Fatal("expected expired, got", err)
+	}
+
+	noTime := newFreshnessTestToken([]byte{1})
+	noTime.ApprovalToken.Timestamp = nil
+	if err := noTime.CheckFreshness(opts); !errors.Is(err, ErrMissingTimestamp) {
+		t.Fatal("expected missing timestamp, got", err)
+	}
+}
+
+func testReplayCache(t *testing.T, cache ReplayCache) {
+	token := newFreshnessTestToken([]byte{1})
+	other := newFreshnessTestToken([]byte{2})
+	issuedAt, _ := token.ApprovalToken.Time()
+	now := issuedAt.Add(time.Minute)
+	opts := &VerifyOptions{
+		MaxAge:      5 * time.Minute,
+		Now:         func() time.Time { return now },
+		ReplayCache: cache,
+	}
+	if err := token.CheckFreshness(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := token.CheckFreshness(opts); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatal("expected replay, got", err)
+	}
+	if err := other.CheckFreshness(opts); err != nil {
+		t.Fatal("other signer must be accepted", err)
+	}
+	opts.MaxAge = 0
+	if err := other.CheckFreshness(opts); !errors.Is(err, ErrReplayCacheWithoutMaxAge) {
+		t.Fatal("expected missing MaxAge, got", err)
+	}
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	cache := NewMemoryReplayCache()
+	testReplayCache(t, cache)
+
+	now := time.Unix(1000, 0)
+	key := NewReplayKey([]byte{1}, []byte{2})
+	lo.Must(cache.Add(key, now, now.Add(time.Second)))
+	if ok := lo.Must(cache.Add(key, now.Add(2*time.Second), time.Time{})); !ok {
+		t.Fatal("expired entry must be pruned")
+	}
+}
+
+func TestFileReplayCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay")
+	testReplayCache(t, lo.Must(NewFileReplayCache(path)))
+
+	// a second instance sees the entries of the first
+	token := newFreshnessTestToken([]byte{1})
+	issuedAt, _ := token.ApprovalToken.Time()
+	opts := &VerifyOptions{
+		MaxAge:      5 * time.Minute,
+		Now:         func() time.Time { return issuedAt },
+		ReplayCache: lo.Must(NewFileReplayCache(path)),
+	}
+	if err := token.CheckFreshness(opts); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatal("expected replay, got", err)
+	}
+
+	// instances sharing the file, like processes, accept a key once
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	key := NewReplayKey([]byte{3}, []byte{4})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lo.Must(lo.Must(NewFileReplayCache(path)).Add(key, issuedAt, issuedAt.Add(time.Minute))) {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Fatal("key accepted", accepted.Load(), "times")
+	}
+}
+
+func TestReplayReencodedKey(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approval := NewPrimusApprovalTokenWithTime(ApprovalTokenOp.SIGN, []byte("payload"), "key",
+		EncodePrimusTimestamp([]byte("payload"), "integrity", now.Unix()), nil)
+	token := lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+	opts := &VerifyOptions{MaxAge: time.Minute, Now: func() time.Time { return now }, ReplayCache: NewMemoryReplayCache()}
+	lo.Must(VerifyAuthorizationToken(token.GetEncoding(), opts))
+
+	// the same signature with the signer key as compressed point
+	compressed := NewPrimusAuthorizationTokenEncode(approval.Serialize(), lo.Must(token.GetVerifySignatureBytes()),
+		EcdsaSignAlg.SHA256withECDSA, compressedPKIX(KeyAlg.SECP256R1, &priv.PublicKey), false)
+	if _, err := VerifyAuthorizationToken(compressed.GetEncoding(), opts); !errors.Is(err, ErrTokenReplayed) {
+		t.Fatal("expected replay, got", err)
+	}
+}
+
+func TestVerifyAuthorizationToken(t *testing.T) {
+	priv := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	approval := NewPrimusApprovalToken(ApprovalTokenOp.BLOCK, nil, "key")
+	token := lo.Must(approval.Authorize(priv, EcdsaSignAlg.SHA256withECDSA))
+
+	verified, err := VerifyAuthorizationToken(token.GetEncoding(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PublicKey.Equal(verified.PublicKey) || verified.Algorithm != EcdsaSignAlg.SHA256withECDSA ||
+		verified.ApprovalToken.Operation != ApprovalTokenOp.BLOCK {
+		t.Fatal("invalid result")
+	}
+
+	challenge := lo.Must(token.GetApprovalTokenBytes())
+	signature := lo.Must(token.GetVerifySignatureBytes())
+
+	other := lo.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	forged := NewPrimusAuthorizationTokenEncode(challenge, signature, EcdsaSignAlg.SHA256withECDSA, lo.Must(x509.MarshalPKIXPublicKey(&other.PublicKey)), false)
+	var sigErr SignatureError
+	if _, err = VerifyAuthorizationToken(forged.GetEncoding(), nil); !errors.As(err, &sigErr) {
+		t.Fatal("expected SignatureError, got", err)
+	}
+
+	missing := NewPrimusAuthorizationTokenEncode(challenge, signature, EcdsaSignAlg.SHA256withECDSA, nil, false)
+	var missingErr MissingFieldError
+	if _, err = VerifyAuthorizationToken(missing.GetEncoding(), nil); !errors.As(err, &missingErr) || missingErr.Field != "PUBLIC_KEY_ENCODED" {
+		t.Fatal("expected MissingFieldError, got", err)
+	}
+
+	malformed := lo.Must(NewPrimusAuthorizationTokenImpl(token.GetEncoding()))
+	malformed.DerSignatureBytes = []byte{0x30, 0x03, 0x02}
+	var malformedErr MalformedSignatureError
+	if _, err = malformed.Verify(nil); !errors.As(err, &malformedErr) {
+		t.Fatal("expected MalformedSignatureError, got", err)
+	}
+
+	edPub, _ := lo.Must2(ed25519.GenerateKey(rand.Reader))
+	edToken := NewPrimusAuthorizationTokenEncode(challenge, signature, EcdsaSignAlg.SHA256withECDSA, lo.Must(x509.MarshalPKIXPublicKey(edPub)), false)
+	var keyErr UnsupportedKeyError
+	if _, err = VerifyAuthorizationToken(edToken.GetEncoding(), nil); !errors.As(err, &keyErr) {
+		t.Fatal("expected UnsupportedKeyError, got", err)
+	}
+
+	// 1.2.840.10045.4.3.5 is not a registered signature algorithm
+	data := token.GetEncoding()
+	unknown := make([]byte, len(data))
+	copy(unknown, data)
+	oid := []byte{42, 134, 72, 206, 61, 4, 3, 2}
+	for i := 0; i+len(oid) <= len(unknown); i++ {
+		if string(unknown[i:i+len(oid)]) == string(oid) {
+			unknown[i+len(oid)-1] = 5
+		}
+	}
+	var algErr UnknownAlgorithmError
+	if _, err = VerifyAuthorizationToken(unknown, nil); !errors.As(err, &algErr) {
+		t.Fatal("expected UnknownAlgorithmError, got", err)
+	}
+	if algErr.Error() != "authorization token: unknown signature algorithm 1.2.840.10045.4.3.5" {
+		t.Fatal("invalid message", algErr.Error())
+	}
+}